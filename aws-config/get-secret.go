@@ -4,6 +4,7 @@ package awsconfig
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -24,16 +25,21 @@ import (
 //
 // password := secret["password"]
 func GetSecret(secretId string) (map[string]string, error) {
+	return GetSecretWithContext(context.TODO(), secretId)
+}
+
+// GetSecretWithContext retrieves a secret like GetSecret, giving up when ctx is done.
+func GetSecretWithContext(ctx context.Context, secretId string) (map[string]string, error) {
 
 	// Configure SDK and create client
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(os.Getenv("REGION")))
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("REGION")))
 	if err != nil {
-		panic("configuration error")
+		return nil, fmt.Errorf("configuration error: %w", err)
 	}
 	client := secretsmanager.NewFromConfig(cfg)
 
 	// Call GetSecretValue
-	output, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+	output, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
 		SecretId: &secretId,
 	})
 
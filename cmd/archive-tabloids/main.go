@@ -0,0 +1,48 @@
+// Command archive-tabloids moves the pages of the tabloids expired for ARCHIVE_AFTER_DAYS days to the archive
+// location. It runs with the same environment as the Lambda function.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	usecase "test/lambda/handler"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	afterDays := flag.Int("after-days", utils.GetEnvInt("ARCHIVE_AFTER_DAYS", 30), "days after the end of validity before a tabloid is archived")
+	dryRun := flag.Bool("dry-run", false, "list the tabloids that would be archived without moving them")
+	flag.Parse()
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		fmt.Println("Erro ao iniciar o uploader:", err)
+		os.Exit(1)
+	}
+	location, err := uploaderservice.ArchiveLocationFromEnv()
+	if err != nil {
+		fmt.Println("Configuração inválida:", err)
+		os.Exit(1)
+	}
+
+	result, err := usecase.ArchiveExpiredTabloids(mysqlservice.NewMysqlTabloideRepository(), uploadService, location,
+		*afterDays, time.Now().UTC(), *dryRun)
+	if result != nil {
+		fmt.Printf("archived: %d %v\n", len(result.Archived), result.Archived)
+	}
+	if err != nil {
+		fmt.Println("Erro ao arquivar:", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Println("dry run, nothing was moved")
+	}
+}
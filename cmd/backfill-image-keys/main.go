@@ -0,0 +1,37 @@
+// Command backfill-image-keys converts the page images stored as CDN URLs into object keys.
+// It is meant to run once, after migration 005, with the same environment as the Lambda function.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	usecase "test/lambda/handler"
+	mysqlservice "test/lambda/services/mysql-service"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	prefix := flag.String("prefix", os.Getenv("CDN_URL"), "URL prefix stripped from the stored URLs to obtain the keys")
+	storage := flag.String("storage", "s3", "storage backend assigned to the converted pages")
+	dryRun := flag.Bool("dry-run", false, "report what would be converted without writing it")
+	flag.Parse()
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	result, err := usecase.BackfillImageKeys(mysqlService, *prefix, *storage, *dryRun)
+	if err != nil {
+		fmt.Println("Erro ao converter as imagens:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("pages: %d, variants: %d, versions: %d, skipped: %d\n", result.Pages, result.Variants, result.Versions, len(result.Skipped))
+	for _, imageURL := range result.Skipped {
+		fmt.Println("skipped:", imageURL)
+	}
+	if *dryRun {
+		fmt.Println("dry run, nothing was written")
+	}
+}
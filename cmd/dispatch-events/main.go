@@ -0,0 +1,54 @@
+// Command dispatch-events writes the validity events of the tabloids published or expired recently and delivers the
+// pending events of the outbox to EVENT_SINK and to the subscribed webhooks. It runs with the same environment as the Lambda function.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	usecase "test/lambda/handler"
+	eventsinkservice "test/lambda/services/event-sink-service"
+	mysqlservice "test/lambda/services/mysql-service"
+	webhookservice "test/lambda/services/webhook-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	lookbackHours := flag.Int("lookback-hours", utils.GetEnvInt("EVENTS_LOOKBACK_HOURS", 48), "how far back to look for tabloids published or expired")
+	batchSize := flag.Int("batch-size", utils.GetEnvInt("OUTBOX_BATCH_SIZE", 100), "events delivered per run")
+	flag.Parse()
+
+	sink, err := eventsinkservice.NewEventSink()
+	if err != nil {
+		fmt.Println("Configuração inválida:", err)
+		os.Exit(1)
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	published, err := usecase.RecordValidityEvents(mysqlService, time.Now(), time.Duration(*lookbackHours)*time.Hour)
+	if err != nil {
+		fmt.Println("Erro ao registrar eventos:", err)
+		os.Exit(1)
+	}
+
+	result, err := usecase.DispatchOutbox(context.Background(), mysqlService, sink, *batchSize, utils.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+		time.Duration(utils.GetEnvInt("OUTBOX_LEASE_SECONDS", 600))*time.Second)
+	if err != nil {
+		fmt.Println("Erro ao enviar eventos:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("validity events: %d, sent: %d, failed: %d\n", published, result.Sent, result.Failed)
+
+	webhooks, err := usecase.DeliverWebhooks(context.Background(), mysqlService, webhookservice.NewWebhookClient(), usecase.WebhookPolicyFromEnv())
+	if err != nil {
+		fmt.Println("Erro ao entregar webhooks:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("webhooks delivered: %d, retried: %d, dead: %d\n", webhooks.Delivered, webhooks.Retried, webhooks.Dead)
+}
@@ -0,0 +1,46 @@
+// Command migrate-image-keys copies the stored page images to the key layout of IMAGE_KEY_TEMPLATE
+// and updates their references. It runs with the same environment as the Lambda function.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	usecase "test/lambda/handler"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	dryRun := flag.Bool("dry-run", false, "report what would be migrated without copying or writing it")
+	flag.Parse()
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		fmt.Println("Erro ao iniciar o uploader:", err)
+		os.Exit(1)
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	result, err := usecase.MigrateImageKeys(mysqlService, uploadService, *dryRun)
+	if result != nil {
+		for oldKey, newKey := range result.Renamed {
+			fmt.Printf("%s -> %s\n", oldKey, newKey)
+		}
+		for _, key := range result.Skipped {
+			fmt.Println("skipped (no checksum):", key)
+		}
+		fmt.Printf("migrated: %d, skipped: %d\n", len(result.Renamed), len(result.Skipped))
+	}
+	if err != nil {
+		fmt.Println("Erro ao migrar as imagens:", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Println("dry run, nothing was copied or written")
+	}
+}
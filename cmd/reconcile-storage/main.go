@@ -0,0 +1,42 @@
+// Command reconcile-storage compares the objects of the S3 bucket with the keys referenced by the database
+// and prints the report as JSON. It runs with the same environment as the Lambda function.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	usecase "test/lambda/handler"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+
+	options := usecase.ReconcileOptionsFromEnv()
+	flag.StringVar(&options.Action, "action", options.Action, "what to do with orphans: report, quarantine or delete")
+	flag.DurationVar(&options.Grace, "grace", options.Grace, "minimum age of an unreferenced object before it is an orphan")
+	flag.StringVar(&options.QuarantinePrefix, "quarantine-prefix", options.QuarantinePrefix, "prefix orphans are moved under by the quarantine action")
+	flag.Parse()
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		fmt.Println("Erro ao iniciar o uploader:", err)
+		os.Exit(1)
+	}
+
+	report, err := usecase.ReconcileStorage(mysqlservice.NewMysqlTabloideRepository(), uploadService, options, time.Now().UTC())
+	if report != nil {
+		encoded, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(encoded))
+	}
+	if err != nil {
+		fmt.Println("Erro na reconciliação:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	urlbuilderservice "test/lambda/services/url-builder-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ArchiveResult summarizes a run of ArchiveExpiredTabloids.
+type ArchiveResult struct {
+	Archived []int64 `json:"arquivados"` // IDs of the archived tabloids.
+}
+
+// ArchiveExpiredTabloids archives the tabloids whose validity ended more than afterDays days before now:
+// their page images move to the archive location and their pages are marked with the archive storage backend.
+// Images also shown by tabloids not archived are copied and kept in the page bucket. The images and the feeds of the
+// archived tabloids are invalidated in the CDN in one batch at the end.
+// When dryRun is set, the tabloids are only listed.
+func ArchiveExpiredTabloids(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	location *uploaderservice.ArchiveLocation, afterDays int, now time.Time, dryRun bool) (*ArchiveResult, error) {
+	tabloids, err := mysqlService.GetTabloidsToArchive(now.Truncate(24*time.Hour).AddDate(0, 0, -afterDays))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ArchiveResult{Archived: []int64{}}
+	auditContext := interfaces.AuditContext{Username: "archive-job", RequestID: uuid.New().String()}
+	stale := utils.CDNPathsFromEnv()
+	// The tabloids archived before a failure are invalidated too
+	defer invalidateCDN(context.Background(), stale)
+	for _, tabloid := range tabloids {
+		if !dryRun {
+			if err := archiveTabloid(mysqlService, uploadService, location, tabloid, auditContext, stale); err != nil {
+				return result, fmt.Errorf("failed to archive tabloid %d: %w", tabloid.ID, err)
+			}
+		}
+		result.Archived = append(result.Archived, tabloid.ID)
+	}
+	return result, nil
+}
+
+// archiveTabloid copies the page images of a tabloid to the archive, points its pages to the archive, then deletes
+// the images no other tabloid shows from the page bucket. Deletion failures are only logged: the objects left
+// behind are orphans the reconciliation removes. Content-addressed images are never deleted here, as an upload may
+// reuse them at any time: the reconciliation removes them once they are unused for the grace period.
+// The paths made stale are added to stale.
+func archiveTabloid(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	location *uploaderservice.ArchiveLocation, tabloid interfaces.Tabloid, auditContext interfaces.AuditContext, stale *utils.CDNPaths) error {
+	pages, err := mysqlService.GetTabloidPages(tabloid.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		if page.ImageKey == "" {
+			return errors.New("page stored without key, run backfill-image-keys first")
+		}
+		if err := uploadService.ArchiveImage(location, page.ImageKey, pageVariantKeys(page)); err != nil {
+			return err
+		}
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	if err := mysqlService.SetTabloidArchived(tabloid.ID, true, interfaces.StorageIDArchive, transaction); err != nil {
+		return err
+	}
+	if err := recordAuditAs(auditContext, mysqlService, transaction, tabloid.ID, interfaces.AuditEntityTabloid,
+		interfaces.AuditActionArchive, nil, location); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return err
+	}
+	stale.AddRegionFeeds(tabloid.RegiaoID)
+	if uploaderservice.ContentAddressed() {
+		return nil
+	}
+
+	for _, page := range pages {
+		shared, err := mysqlService.CountImageReferences(page.ImageKey, tabloid.ID)
+		if err == nil && shared == 0 {
+			err = uploadService.DeleteImage(page.ImageKey, pageVariantKeys(page))
+		}
+		if err != nil {
+			fmt.Println("err de DeleteImage", page.ImageKey, err)
+		}
+	}
+	return nil
+}
+
+// pageVariantKeys returns the keys of the resized copies of a page.
+func pageVariantKeys(page interfaces.Page) []string {
+	keys := make([]string, 0, len(page.Variants))
+	for _, variant := range page.Variants {
+		if variant.Key != "" {
+			keys = append(keys, variant.Key)
+		}
+	}
+	return keys
+}
+
+// HandleArchiveTabloidsEvent handles the scheduled EventBridge event that archives the tabloids expired for
+// ARCHIVE_AFTER_DAYS days (30 by default).
+func HandleArchiveTabloidsEvent(ctx context.Context, event events.CloudWatchEvent) (*ArchiveResult, error) {
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return nil, err
+	}
+	location, err := uploaderservice.ArchiveLocationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ArchiveExpiredTabloids(mysqlservice.NewMysqlTabloideRepository(), uploadService, location,
+		utils.GetEnvInt("ARCHIVE_AFTER_DAYS", 30), time.Now().UTC(), false)
+	if result != nil {
+		fmt.Println("tabloides arquivados:", result.Archived)
+	}
+	return result, err
+}
+
+// HandleRestoreArchivedTabloidRequest handles POST requests bringing an archived tabloid back: its page images are
+// copied from the archive to the page bucket and its pages point to them again. The archived copies are kept.
+// The restore is recorded, so the archive job does not archive the tabloid again until its validity is extended and
+// ends again. The CDN may have cached the images as missing, so they are invalidated along with the feeds of the region.
+// It responds with 409 if the tabloid is not archived.
+func HandleRestoreArchivedTabloidRequest(c *gin.Context) {
+	tabloidID, err := parseTabloidID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	tabloid, err := mysqlService.GetTabloidById(tabloidID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Tabloid not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de GetTabloidById", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	archived, err := mysqlService.IsTabloidArchived(tabloidID)
+	if err != nil {
+		fmt.Println("err de IsTabloidArchived", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	if !archived {
+		c.JSON(http.StatusConflict, Response{Error: "Tabloid is not archived"})
+		return
+	}
+	pages, err := mysqlService.GetTabloidPages(tabloidID)
+	if err != nil {
+		fmt.Println("err de GetTabloidPages", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err == nil {
+		err = restoreArchivedTabloid(c, mysqlService, uploadService, tabloidID, pages)
+	}
+	if err != nil {
+		fmt.Println("err de restoreArchivedTabloid", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	// The images were missing from the page bucket while archived, so the CDN may have cached them as not found
+	stale := utils.CDNPathsFromEnv()
+	for _, page := range pages {
+		stale.AddPage(page)
+	}
+	stale.AddRegionFeeds(tabloid.RegiaoID)
+	invalidateCDN(c.Request.Context(), stale)
+
+	urlBuilder, err := urlbuilderservice.NewURLBuilder()
+	if err == nil {
+		err = resolvePageURLs(urlBuilder, pages, *tabloid)
+	}
+	if err != nil {
+		fmt.Println("err de resolvePageURLs", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tabloide": tabloid, "paginas": pages})
+}
+
+// restoreArchivedTabloid copies the page images of an archived tabloid back to the page bucket and points its pages
+// to them, recording the audit trail. pages are updated with their new storage backend.
+func restoreArchivedTabloid(c *gin.Context, mysqlService *mysqlservice.MysqlTabloideRepository,
+	uploadService *uploaderservice.UploaderAdapter, tabloidID int64, pages []interfaces.Page) error {
+	location, err := uploaderservice.ArchiveLocationFromEnv()
+	if err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if err := uploadService.RestoreArchivedImage(location, page.ImageKey, pageVariantKeys(page)); err != nil {
+			return err
+		}
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	if err := mysqlService.SetTabloidArchived(tabloidID, false, storageID(), transaction); err != nil {
+		return err
+	}
+	if err := recordAudit(c, mysqlService, transaction, tabloidID, interfaces.AuditEntityTabloid,
+		interfaces.AuditActionUnarchive, location, nil); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return err
+	}
+
+	for i := range pages {
+		pages[i].StorageID = storageID()
+	}
+	return nil
+}
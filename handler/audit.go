@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	"test/lambda/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetAuditRequest handles GET requests for the audit history of one tabloid.
+// It responds with every recorded mutation of the tabloid, most recent first.
+func HandleGetAuditRequest(c *gin.Context) {
+	tabloidID, err := parseTabloidID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	entries, err := mysqlService.GetAuditEntriesByTabloidId(tabloidID)
+	if err != nil {
+		fmt.Println("err de GetAuditEntriesByTabloidId", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// recordAudit inserts an audit entry describing a mutation performed by the current request.
+// before and after are serialized as JSON; pass nil when the entity did not exist before or after the mutation.
+func recordAudit(c *gin.Context, mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	tabloidID int64, entity, action string, before, after interface{}) error {
+	return recordAuditAs(utils.GetAuditContext(c), mysqlService, transaction, tabloidID, entity, action, before, after)
+}
+
+// recordAuditAs inserts an audit entry describing a mutation performed outside of a request, such as by a job.
+func recordAuditAs(auditContext interfaces.AuditContext, mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	tabloidID int64, entity, action string, before, after interface{}) error {
+	entry := interfaces.AuditEntry{
+		TabloidID: tabloidID,
+		Entity:    entity,
+		Action:    action,
+		Actor:     auditContext.Username,
+		RequestID: auditContext.RequestID,
+	}
+
+	var err error
+	if entry.Before, err = marshalAuditValue(before); err != nil {
+		return err
+	}
+	if entry.After, err = marshalAuditValue(after); err != nil {
+		return err
+	}
+
+	return mysqlService.InsertAuditEntry(entry, transaction)
+}
+
+// marshalAuditValue serializes an audited value, keeping nil values as NULL.
+func marshalAuditValue(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit value: %v", err)
+	}
+	return data, nil
+}
+
+// parseTabloidID reads the :id path parameter as a tabloid ID.
+func parseTabloidID(c *gin.Context) (int64, error) {
+	tabloidID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || tabloidID < 1 {
+		return 0, fmt.Errorf("invalid tabloid id: %s", c.Param("id"))
+	}
+	return tabloidID, nil
+}
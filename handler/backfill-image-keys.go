@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	mysqlservice "test/lambda/services/mysql-service"
+)
+
+// BackfillResult summarizes a run of BackfillImageKeys.
+type BackfillResult struct {
+	Pages    int      // Pages given a key.
+	Variants int64    // Resized copies given a key.
+	Versions int      // Versions whose pages were rewritten as keys.
+	Skipped  []string // URLs not starting with the prefix, left untouched.
+}
+
+// BackfillImageKeys converts the pages, resized copies and versions stored before image keys were introduced,
+// deriving each key by stripping prefix (the CDN_URL the URLs were built with) from the stored URL.
+// Converted pages are assigned to the given storage backend. Everything is written in one transaction,
+// which is rolled back when dryRun is set, so the run can be repeated safely.
+func BackfillImageKeys(mysqlService *mysqlservice.MysqlTabloideRepository, prefix, storage string, dryRun bool) (*BackfillResult, error) {
+	if prefix == "" {
+		return nil, errors.New("empty URL prefix")
+	}
+
+	pages, err := mysqlService.GetPagesWithoutImageKey()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := mysqlService.GetAllTabloidVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	result := &BackfillResult{}
+	for _, page := range pages {
+		if !strings.HasPrefix(page.ImageURL, prefix) {
+			result.Skipped = append(result.Skipped, page.ImageURL)
+			continue
+		}
+		if err := mysqlService.UpdatePageImageKey(page.ID, strings.TrimPrefix(page.ImageURL, prefix), storage, transaction); err != nil {
+			return nil, err
+		}
+		result.Pages++
+	}
+
+	result.Variants, err = mysqlService.BackfillVariantKeys(prefix, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range versions {
+		changed := false
+		for i, page := range version.Pages {
+			if strings.HasPrefix(page, prefix) {
+				version.Pages[i] = strings.TrimPrefix(page, prefix)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := mysqlService.UpdateTabloidVersionPages(version.ID, version.Pages, transaction); err != nil {
+			return nil, err
+		}
+		result.Versions++
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	cdnservice "test/lambda/services/cdn-service"
+	"test/lambda/utils"
+	"time"
+)
+
+// invalidateCDN invalidates the collected paths in the CDN selected by CDN_INVALIDATION, in one batch, giving up
+// after CDN_INVALIDATION_TIMEOUT_SECONDS (3 by default) so requests are not held by the CDN.
+// Failures are only logged: the change is already committed, and the stale copies expire with their cache anyway.
+func invalidateCDN(ctx context.Context, paths *utils.CDNPaths) {
+	if paths.Len() == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(utils.GetEnvInt("CDN_INVALIDATION_TIMEOUT_SECONDS", 3))*time.Second)
+	defer cancel()
+
+	invalidator, err := cdnservice.NewInvalidator()
+	if err == nil {
+		err = invalidator.Invalidate(ctx, paths.Paths())
+	}
+	if err != nil {
+		fmt.Println("err de Invalidate", paths.Paths(), err)
+	}
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"test/lambda/interfaces"
+	cdnservice "test/lambda/services/cdn-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordInvalidations selects the in-memory invalidator for the test and returns it, emptied.
+func recordInvalidations(t *testing.T) *cdnservice.RecordingInvalidator {
+	t.Helper()
+	t.Setenv("CDN_INVALIDATION", "memory")
+	invalidator, err := cdnservice.NewInvalidator()
+	if err != nil {
+		t.Fatalf("NewInvalidator returned error: %v", err)
+	}
+	recorder := invalidator.(*cdnservice.RecordingInvalidator)
+	recorder.Reset()
+	return recorder
+}
+
+func TestInvalidateCDN(t *testing.T) {
+	recorder := recordInvalidations(t)
+	t.Setenv("CDN_URL", "https://cdn.example.com/images")
+	t.Setenv("CDN_FEED_PATH_PREFIX", "/dev")
+
+	invalidateCDN(context.Background(), utils.CDNPathsFromEnv())
+	if invalidations := recorder.Invalidations(); len(invalidations) != 0 {
+		t.Fatalf("invalidateCDN invalidated %v with no stale path", invalidations)
+	}
+
+	stale := utils.CDNPathsFromEnv()
+	stale.AddPage(interfaces.Page{ImageKey: "tabloides/42/0.png", Variants: []interfaces.PageVariant{{Width: 200, Key: "tabloides/42/0-200.png"}}})
+	stale.AddRegionFeeds(144)
+	invalidateCDN(context.Background(), stale)
+
+	expected := [][]string{{
+		"/dev/regions/144/calendar.ics",
+		"/dev/regions/144/feed.atom",
+		"/dev/regions/144/tabloids",
+		"/images/tabloides/42/0-200.png",
+		"/images/tabloides/42/0.png",
+	}}
+	if invalidations := recorder.Invalidations(); !reflect.DeepEqual(invalidations, expected) {
+		t.Errorf("invalidateCDN invalidated %v, expected %v", invalidations, expected)
+	}
+}
+
+func TestArchiveExpiredTabloidsInvalidatesFeeds(t *testing.T) {
+	recorder := recordInvalidations(t)
+	t.Setenv("CDN_FEED_PATH_PREFIX", "")
+	mysqlService, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE dt_arquivamento IS NULL AND dt_fim_vigencia < ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "nome", "regiao_id", "dt_inicio_vigencia", "dt_fim_vigencia", "ativo", "dt_cadastro", "dt_alteracao"}).
+			AddRow(42, "Ofertas de agosto", 144, "2026-08-01", "2026-08-31", 1, "2026-07-30 10:00:00", "2026-07-30 10:00:00"))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM imagem_tabloide WHERE tabloide_id = ?")).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tabloide_id", "imagem_url", "imagem_key", "storage_id", "checksum_sha256", "ordem"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SET dt_arquivamento = NOW()")).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE imagem_tabloide SET storage_id = ?")).WithArgs(interfaces.StorageIDArchive, 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO auditoria_tabloide")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	now := time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)
+	result, err := ArchiveExpiredTabloids(mysqlService, &uploaderservice.UploaderAdapter{}, &uploaderservice.ArchiveLocation{Prefix: "archive/"},
+		30, now, false)
+	if err != nil {
+		t.Fatalf("ArchiveExpiredTabloids returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Archived, []int64{42}) {
+		t.Errorf("ArchiveExpiredTabloids archived %v, expected [42]", result.Archived)
+	}
+
+	expected := [][]string{{"/regions/144/calendar.ics", "/regions/144/feed.atom", "/regions/144/tabloids"}}
+	if invalidations := recorder.Invalidations(); !reflect.DeepEqual(invalidations, expected) {
+		t.Errorf("ArchiveExpiredTabloids invalidated %v, expected %v", invalidations, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+)
+
+// statusError is a failure of a use case carrying the HTTP status it maps to.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// withStatus attaches the HTTP status a failure maps to.
+func withStatus(status int, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+// errorStatus returns the HTTP status attached to err by withStatus, or 500.
+func errorStatus(err error) int {
+	var withStatus *statusError
+	if errors.As(err, &withStatus) {
+		return withStatus.status
+	}
+	return http.StatusInternalServerError
+}
+
+// createHooks lets the callers of createTabloid follow its progress. Every hook is optional.
+type createHooks struct {
+	onPagesRead func(total int)                                  // Called once the pages of the upload are read.
+	onPage      func(result interfaces.JobPage)                  // Called as each page ends, from the uploading goroutines.
+	onCommit    func(transaction *sql.Tx, tabloidID int64) error // Called inside the transaction, right before it commits.
+}
+
+// createTabloid runs the create use case shared by the HTTP API, the jobs and the tabloid queue: it inserts the
+// tabloid of formData, uploads the pages of files (expanding ZIP archives), records the pages, the audit trail and
+// the first version, and commits. The feeds of the region are then invalidated in the CDN.
+// When it fails, nothing is committed and the images it stored are deleted; the error carries the HTTP status it
+// maps to (see errorStatus): a 4xx when the request is rejected, a 5xx when the database or S3 failed, worth retrying.
+func createTabloid(ctx context.Context, mysqlService *mysqlservice.MysqlTabloideRepository, auditContext interfaces.AuditContext,
+	formData interfaces.RequestEvent, files []interfaces.File, limits utils.UploadLimits, hooks createHooks) (*interfaces.Tabloid, []interfaces.Page, error) {
+	// Initialize upload service for uploading images
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Retrieve region by ID from MySQL service
+	if _, err := mysqlService.GetRegionById(formData.RegionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, withStatus(http.StatusBadRequest, errors.New("Region not found"))
+		}
+		return nil, nil, err
+	}
+
+	// Read the page images, expanding ZIP archives into one page per entry
+	pageImages, err := readPageImages(files, uploadService, limits)
+	if err != nil {
+		return nil, nil, withStatus(uploadErrorStatus(err), err)
+	}
+	if hooks.onPagesRead != nil {
+		hooks.onPagesRead(len(pageImages))
+	}
+
+	// Read the widths of the resized copies of each page
+	variantWidths, err := imageVariantWidths()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer transaction.Rollback()
+
+	// Insert tabloid data into database
+	tabloidID, err := mysqlService.InsertTabloid(formData.Name, formData.RegionID, formData.StartValidityDate, formData.EndValidityDate, transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tabloid := interfaces.Tabloid{
+		ID:               tabloidID,
+		Nome:             formData.Name,
+		DtInicioVigencia: formData.StartValidityDate,
+		DtFimVigencia:    formData.EndValidityDate,
+		Ativo:            true,
+		RegiaoID:         formData.RegionID,
+	}
+
+	// Upload the pages concurrently, then insert them in page order: the transaction is not safe for concurrent use
+	uploaded, err := uploadPages(ctx, uploadService, pageImages, tabloid, auditContext.Username, variantWidths, hooks.onPage)
+	if err != nil {
+		transaction.Rollback()
+		deleteUploadedPages(mysqlService, uploadService, uploaded)
+		return nil, nil, withStatus(pageUploadErrorStatus(err), err)
+	}
+	pages, err := recordCreatedTabloid(mysqlService, transaction, auditContext, tabloid, uploaded, hooks)
+	if err == nil {
+		err = transaction.Commit()
+	}
+	if err != nil {
+		transaction.Rollback()
+		deleteUploadedPages(mysqlService, uploadService, uploaded)
+		return nil, nil, err
+	}
+
+	stale := utils.CDNPathsFromEnv()
+	stale.AddRegionFeeds(tabloid.RegiaoID)
+	invalidateCDN(ctx, stale)
+
+	return &tabloid, pages, nil
+}
+
+// recordCreatedTabloid inserts the uploaded pages of a new tabloid, its audit trail, its first version and the
+// TabloidCreated event inside the transaction, then runs the onCommit hook.
+func recordCreatedTabloid(mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx, auditContext interfaces.AuditContext,
+	tabloid interfaces.Tabloid, uploaded []uploadedPage, hooks createHooks) ([]interfaces.Page, error) {
+	pages, pageKeys, err := insertPages(mysqlService, transaction, tabloid.ID, uploaded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the audit trail and the first version of the created tabloid
+	if err := recordAuditAs(auditContext, mysqlService, transaction, tabloid.ID, interfaces.AuditEntityTabloid, interfaces.AuditActionCreate, nil, tabloid); err != nil {
+		return nil, err
+	}
+	if err := recordAuditAs(auditContext, mysqlService, transaction, tabloid.ID, interfaces.AuditEntityPage, interfaces.AuditActionPageChange, nil, pages); err != nil {
+		return nil, err
+	}
+	if _, err := snapshotVersionAs(auditContext, mysqlService, transaction, tabloid, pageKeys, nil); err != nil {
+		return nil, err
+	}
+	if err := recordEvent(mysqlService, transaction, interfaces.EventTabloidCreated, tabloid, pageKeys, ""); err != nil {
+		return nil, err
+	}
+
+	if hooks.onCommit != nil {
+		if err := hooks.onCommit(transaction, tabloid.ID); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
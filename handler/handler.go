@@ -2,12 +2,11 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"test/lambda/interfaces"
 	mysqlservice "test/lambda/services/mysql-service"
-	uploaderservice "test/lambda/services/uploader-service"
 	"test/lambda/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,107 +14,86 @@ import (
 
 type Response struct {
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
 	Request interface{} `json:"request,omitempty"`
 	Context interface{} `json:"context,omitempty"`
 }
 
-// HandlePostRequest handles POST requests to upload tabloid data.
-// It parses the multipart form data, validates the request event,
-// performs database operations to insert tabloid data, uploads images,
-// and commits the transaction.
-func HandlePostRequest(c *gin.Context) {
-	// Parse the multipart form data
-	if err := c.Request.ParseMultipartForm(0); err != nil {
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
+// errorResponse builds the response of a failed request, exposing the code of image validation and upload size errors.
+func errorResponse(err error) Response {
+	response := Response{Error: err.Error()}
+	var validationError *utils.ImageValidationError
+	var tooLargeError *utils.UploadTooLargeError
+	if errors.As(err, &validationError) {
+		response.Code = validationError.Code
+	} else if errors.As(err, &tooLargeError) {
+		response.Code = utils.ErrorUploadTooLarge
 	}
+	return response
+}
 
-	// Bind the form data to the RequestEvent struct
-	var request interfaces.RequestEvent
-	if err := c.ShouldBind(&request); err != nil {
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
-	}
-
-	// Validate the request event struct
-	if err := utils.ValidateStruct(request); err != nil {
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
-	}
-
-	// Initialize MySQL service for tabloid repository
-	mysqlService := mysqlservice.NewMysqlTabloideRepository()
-	transaction, _ := mysqlService.GetTransaction()
-
-	// Initialize upload service for uploading images
-	uploadService, err := uploaderservice.NewUploaderAdapter()
-	if err != nil {
-		fmt.Println("err de uploadService", err)
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
+// uploadErrorStatus returns the status of a rejected upload: 413 when it exceeds the upload limits, 400 otherwise.
+func uploadErrorStatus(err error) int {
+	var tooLargeError *utils.UploadTooLargeError
+	if errors.As(err, &tooLargeError) {
+		return http.StatusRequestEntityTooLarge
 	}
+	return http.StatusBadRequest
+}
 
-	// Parse multipart form data
-	err = c.Request.ParseMultipartForm(10 << 20) // 10mb de tamanho máximo TODO VERIFICAR TAMANHO MÁXIMO
-	if err != nil {
-		fmt.Println("err de ParseMultipartFomr", err)
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
+// pageUploadErrorStatus returns the status of a failed upload of the pages: 400 when an image is rejected, 500 when
+// S3 failed.
+func pageUploadErrorStatus(err error) int {
+	var validationError *utils.ImageValidationError
+	if errors.As(err, &validationError) {
+		return http.StatusBadRequest
 	}
+	return http.StatusInternalServerError
+}
 
-	// Parse form data into RequestEvent object
-	formData, err := utils.ParseFormData(c)
+// HandlePostRequest handles POST requests to upload tabloid data.
+// It streams the multipart form data within UPLOAD_MAX_PAGE_BYTES and UPLOAD_MAX_REQUEST_BYTES (413 beyond them),
+// validates the request event, performs database operations to insert tabloid data, uploads images
+// (UPLOAD_CONCURRENCY pages at a time) and commits the transaction.
+// When the request fails, the images it stored are deleted.
+// With ?async=true, the tabloid is created by a job instead: it responds 202 with the job, whose progress
+// GET /jobs/:id reports.
+func HandlePostRequest(c *gin.Context) {
+	// Stream the multipart form, reading the file once and within the upload limits
+	uploadLimits := utils.UploadLimitsFromEnv()
+	formData, err := utils.ParseFormData(c, uploadLimits)
 	if err != nil {
 		fmt.Println("err de ParseFormData", err)
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		c.JSON(uploadErrorStatus(err), errorResponse(err))
 		return
 	}
+	defer utils.RemoveSpooledFiles(formData.File)
 
-	// Retrieve region by ID from MySQL service
-	region, err := mysqlService.GetRegionById(formData.RegionID)
-	if err != nil || region == nil {
-		fmt.Println("err de GetRegionById", err)
-		c.JSON(http.StatusBadRequest, Response{Error: "Region not found"})
-		return
-	}
-
-	// Insert tabloid data into database
-	tabloidID, err := mysqlService.InsertTabloid(formData.Name, formData.RegionID, formData.StartValidityDate, formData.EndValidityDate, transaction)
-	if err != nil {
-		fmt.Println("err de InsertTabloid", err)
+	// Validate the request event struct
+	if err := utils.ValidateStruct(formData); err != nil {
 		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
 
-	// Read file content and upload image
-	convertedImageContent, err := utils.ReadFileContent(formData.File.Data)
-	if err != nil {
-		fmt.Println("ReadFileContent", err)
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
-		return
-	}
-	var order = 0
-	imageUrl, err := uploadService.UploadImage(convertedImageContent, tabloidID, order)
-	if err != nil {
-		fmt.Println("UploadImage", err)
-		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+	if c.Query("async") == "true" {
+		job, err := enqueueCreateJob(c.Request.Context(), utils.GetAuditContext(c), *formData)
+		if err != nil {
+			fmt.Println("err de enqueueCreateJob", err)
+			c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+			return
+		}
+		c.Header("Location", "/jobs/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
 		return
 	}
 
-	// Format image URL
-	formatedImageUrl := fmt.Sprintf("%s%s", os.Getenv("CDN_URL"), imageUrl)
-	/* fmt.Println(formatedImageUrl) */
-
-	// Insert tabloid image into database
-	err = mysqlService.InsertTabloidImage(formatedImageUrl, tabloidID, order, transaction)
-	if err != nil {
-		fmt.Println("Error uploading image:", err)
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	if _, _, err := createTabloid(c.Request.Context(), mysqlService, utils.GetAuditContext(c), *formData, []interfaces.File{formData.File}, uploadLimits, createHooks{}); err != nil {
+		fmt.Println("err de createTabloid", err)
+		c.JSON(errorStatus(err), errorResponse(err))
 		return
 	}
 
-	// Commit the transaction
-	transaction.Commit()
-
 	// Respond with success
 	c.JSON(http.StatusOK, formData)
 }
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+	awsconfig "test/lambda/aws-config"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the version the binary was built from, set at build time with
+// -ldflags "-X test/lambda/handler.Version=...".
+var Version = "dev"
+
+// startedAt is when the process started.
+var startedAt = time.Now()
+
+// HandleHealthRequest handles GET requests checking that the function is alive. It checks no dependency.
+func HandleHealthRequest(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": interfaces.HealthStatusOK})
+}
+
+// HandleReadyRequest handles GET requests checking that the function can reach its dependencies: it reads the database
+// secret, pings MySQL and heads the bucket, concurrently, each within READINESS_TIMEOUT_SECONDS (3 by default).
+// It responds with 503 if any check fails, with the latency and the status of each one; why a check failed is only
+// shown by the diagnostics endpoint.
+func HandleReadyRequest(c *gin.Context) {
+	report := runReadinessChecks(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != interfaces.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, utils.PublicReadinessReport(report))
+}
+
+// HandleDiagnosticsRequest handles GET requests for the build version, the configuration of the function with its
+// secrets masked, the stats of the MySQL connection pool and the readiness checks with their errors.
+// Its route requires the authorizer.
+func HandleDiagnosticsRequest(c *gin.Context) {
+	diagnostics := interfaces.Diagnostics{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		StartedAt: startedAt,
+		Config:    utils.MaskedConfig(os.Environ()),
+		Readiness: runReadinessChecks(c.Request.Context()),
+	}
+	if mysqlService, err := mysqlservice.NewMysqlTabloideRepositoryWithContext(c.Request.Context()); err == nil {
+		pool := mysqlService.PoolStats()
+		diagnostics.Pool = &pool
+	}
+	c.JSON(http.StatusOK, diagnostics)
+}
+
+// runReadinessChecks reads the database secret, pings MySQL and heads the bucket, each within READINESS_TIMEOUT_SECONDS.
+func runReadinessChecks(ctx context.Context) interfaces.ReadinessReport {
+	checks := []utils.ReadinessCheck{
+		{Name: "secret", Check: func(ctx context.Context) error {
+			_, err := awsconfig.GetSecretWithContext(ctx, os.Getenv("SECRET_ID_MYSQL"))
+			return err
+		}},
+		{Name: "mysql", Check: func(ctx context.Context) error {
+			mysqlService, err := mysqlservice.NewMysqlTabloideRepositoryWithContext(ctx)
+			if err != nil {
+				return err
+			}
+			return mysqlService.Ping(ctx)
+		}},
+		{Name: "s3", Check: func(ctx context.Context) error {
+			uploadService, err := uploaderservice.NewUploaderAdapter()
+			if err != nil {
+				return err
+			}
+			return uploadService.HeadBucket(ctx)
+		}},
+	}
+	timeout := time.Duration(utils.GetEnvInt("READINESS_TIMEOUT_SECONDS", 3)) * time.Second
+	return utils.RunReadinessChecks(ctx, timeout, checks)
+}
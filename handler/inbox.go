@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// HandleInboxEvent handles the S3 events of the inbox, where systems that cannot call the API, such as the RPA robot,
+// drop tabloids as a folder under INBOX_PREFIX holding the page files and a manifest.json (a TabloidManifest whose
+// files are relative to the folder). The manifest must be written last, as it triggers the creation.
+// Once processed, the folder is moved under INBOX_DONE_PREFIX, or under INBOX_FAILED_PREFIX with an error.json report.
+func HandleInboxEvent(ctx context.Context, event events.S3Event) error {
+	layout, err := utils.InboxLayoutFromEnv()
+	if err != nil {
+		return err
+	}
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		// Keys are URL-encoded in S3 events
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			fmt.Println("invalid key in S3 event", record.S3.Object.Key, err)
+			continue
+		}
+		if record.S3.Bucket.Name != os.Getenv("AWS_S3_BUCKET_NAME_S3") || !layout.IsManifest(key) {
+			continue
+		}
+		if err := processInboxManifest(ctx, layout, uploadService, key, record.S3.Object.ETag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processInboxManifest creates the tabloid of a manifest dropped in the inbox and moves its files out of the inbox.
+// S3 events are delivered at least once, so manifests no longer in the inbox are skipped, and the key and the ETag of
+// the manifest are the idempotency key of the create: a manifest whose files could not be moved after its tabloid
+// was created only has its files moved when it is delivered again.
+// A manifest rejected by the create use case (a 4xx) is moved under the failed prefix. It returns an error when the
+// manifest could not be looked up or the database or S3 failed (a 5xx), so the event is delivered again.
+func processInboxManifest(ctx context.Context, layout utils.InboxLayout, uploadService *uploaderservice.UploaderAdapter,
+	manifestKey, eTag string) error {
+	exists, err := uploadService.ObjectExists(manifestKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		fmt.Println("inbox: manifest already processed", manifestKey)
+		return nil
+	}
+
+	keys := []string{manifestKey}
+	auditContext := interfaces.AuditContext{Username: "s3-inbox", RequestID: uuid.New().String()}
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	tabloid, err := createFromInboxManifest(ctx, layout, mysqlService, uploadService, auditContext, manifestKey,
+		"s3-inbox:"+manifestKey+":"+eTag, &keys)
+	if err != nil {
+		fmt.Println("err de createFromInboxManifest", manifestKey, err)
+		if errorStatus(err) >= http.StatusInternalServerError {
+			// The manifest stays in the inbox for the event to be delivered again
+			return err
+		}
+		failInboxManifest(ctx, layout, uploadService, manifestKey, keys, err)
+		return nil
+	}
+
+	fmt.Printf("inbox: %s processed, tabloid %d\n", manifestKey, tabloid.ID)
+	for _, key := range keys {
+		if err := uploadService.MoveObject(key, layout.DoneKey(key)); err != nil {
+			fmt.Println("err de MoveObject", key, err)
+		}
+	}
+	return nil
+}
+
+// createFromInboxManifest reads the manifest stored under manifestKey and creates its tabloid, unless the manifest
+// was already processed under idempotencyKey: the tabloid created then is returned, with only its ID.
+// The keys of the files it lists are appended to keys as they are resolved, so they can be moved even on failure.
+func createFromInboxManifest(ctx context.Context, layout utils.InboxLayout, mysqlService *mysqlservice.MysqlTabloideRepository,
+	uploadService *uploaderservice.UploaderAdapter, auditContext interfaces.AuditContext, manifestKey, idempotencyKey string,
+	keys *[]string) (*interfaces.Tabloid, error) {
+	body, err := uploadService.ReadObject(ctx, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := utils.ParseTabloidManifest(body)
+	if err != nil {
+		return nil, withStatus(http.StatusBadRequest, err)
+	}
+
+	fileKeys := map[string]string{}
+	for _, name := range manifest.Files {
+		key, err := layout.FileKey(manifestKey, name)
+		if err != nil {
+			return nil, withStatus(http.StatusBadRequest, err)
+		}
+		fileKeys[name] = key
+		*keys = append(*keys, key)
+	}
+
+	tabloidID, err := mysqlService.GetProcessedRequest(idempotencyKey)
+	if err == nil {
+		fmt.Printf("inbox: %s already created tabloid %d\n", manifestKey, tabloidID)
+		return &interfaces.Tabloid{ID: tabloidID}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return createFromManifest(ctx, auditContext, mysqlService, idempotencyKey, *manifest, func(ctx context.Context, name string) (io.ReadCloser, error) {
+		return uploadService.OpenObject(ctx, fileKeys[name])
+	})
+}
+
+// failInboxManifest moves the files of a failed manifest under the failed prefix and writes the error report.
+// Failures are only logged: the files left in the inbox do not trigger again, only a new manifest does.
+func failInboxManifest(ctx context.Context, layout utils.InboxLayout, uploadService *uploaderservice.UploaderAdapter,
+	manifestKey string, keys []string, cause error) {
+	response := errorResponse(cause)
+	report := interfaces.InboxErrorReport{
+		Manifest: manifestKey,
+		Error:    response.Error,
+		Code:     response.Code,
+		Files:    []string{},
+		FailedAt: time.Now(),
+	}
+
+	for _, key := range keys {
+		exists, err := uploadService.ObjectExists(key)
+		if err == nil && exists {
+			err = uploadService.MoveObject(key, layout.FailedKey(key))
+		}
+		if err != nil {
+			fmt.Println("err de MoveObject", key, err)
+			continue
+		}
+		if exists {
+			report.Files = append(report.Files, layout.FailedKey(key))
+		}
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = uploadService.WriteObject(ctx, layout.ErrorReportKey(manifestKey), bytes.NewReader(body), "application/json")
+	}
+	if err != nil {
+		fmt.Println("err de WriteObject", layout.ErrorReportKey(manifestKey), err)
+	}
+}
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	queueservice "test/lambda/services/queue-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// localJobQueue is the in-process queue used when JOB_QUEUE is "local", created on first use.
+var localJobQueue = sync.OnceValue(func() *queueservice.LocalQueue {
+	return queueservice.NewLocalQueue(consumeJobMessage, 1)
+})
+
+// newJobQueue creates the queue selected by JOB_QUEUE: "sqs" (default) sends to the queue at JOB_QUEUE_URL,
+// consumed by HandleJobQueueEvent; "local" processes the jobs in this process, to run without AWS.
+func newJobQueue() (queueservice.Queue, error) {
+	switch mode := os.Getenv("JOB_QUEUE"); mode {
+	case "", "sqs":
+		return queueservice.NewSQSQueue(os.Getenv("JOB_QUEUE_URL"))
+	case "local":
+		return localJobQueue(), nil
+	default:
+		return nil, fmt.Errorf("invalid JOB_QUEUE: %q", mode)
+	}
+}
+
+// readStagedFile reads the file staged for a job back from S3, within the upload limits: a ZIP archive is kept in a
+// temporary file, as when it was uploaded.
+func readStagedFile(ctx context.Context, uploadService *uploaderservice.UploaderAdapter, job *interfaces.Job) (interfaces.File, error) {
+	body, err := uploadService.OpenObject(ctx, job.FileKey)
+	if err != nil {
+		return interfaces.File{}, err
+	}
+	defer body.Close()
+
+	file, err := utils.FileFromReader(job.Request.File.Name, body, utils.UploadLimitsFromEnv())
+	if err != nil {
+		return file, err
+	}
+	file.ContentType = job.Request.File.ContentType
+	return file, nil
+}
+
+// enqueueCreateJob stages the uploaded file in S3, records a queued job for formData and sends it to the job queue.
+// It returns the created job.
+func enqueueCreateJob(ctx context.Context, auditContext interfaces.AuditContext, formData interfaces.RequestEvent) (*interfaces.Job, error) {
+	queue, err := newJobQueue()
+	if err != nil {
+		return nil, err
+	}
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return nil, err
+	}
+
+	job := interfaces.Job{
+		ID:        uuid.New().String(),
+		Status:    interfaces.JobStatusQueued,
+		Pages:     []interfaces.JobPage{},
+		Request:   formData,
+		Actor:     auditContext.Username,
+		RequestID: auditContext.RequestID,
+	}
+	job.FileKey = uploaderservice.StagedUploadKey(job.ID, formData.File.Name)
+	content, err := utils.OpenFile(formData.File)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	if err := uploadService.WriteObject(ctx, job.FileKey, content, formData.File.ContentType); err != nil {
+		return nil, err
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	if err := mysqlService.InsertJob(job); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(interfaces.JobMessage{JobID: job.ID})
+	if err != nil {
+		return nil, err
+	}
+	if err := queue.Send(ctx, body); err != nil {
+		// The job would never run: record why instead of leaving it queued
+		if statusErr := mysqlService.UpdateJobStatus(job.ID, interfaces.JobStatusFailed, err.Error(), ""); statusErr != nil {
+			fmt.Println("err de UpdateJobStatus", job.ID, statusErr)
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// HandleGetJobRequest handles GET requests for the status of one job: its progress, the result of each page
+// processed so far and, once ended, the created tabloid or the error.
+func HandleGetJobRequest(c *gin.Context) {
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	job, err := mysqlService.GetJob(c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Job not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de GetJob", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// HandleJobQueueEvent handles the SQS events of the job queue, processing the job of each message.
+// It returns an error, so the batch is delivered again, if any job could not be processed.
+func HandleJobQueueEvent(ctx context.Context, event events.SQSEvent) error {
+	var failed []string
+	for _, record := range event.Records {
+		if err := consumeJobMessage(ctx, []byte(record.Body)); err != nil {
+			fmt.Println("err de consumeJobMessage", record.MessageId, err)
+			failed = append(failed, record.MessageId)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to process messages %v", failed)
+	}
+	return nil
+}
+
+// consumeJobMessage processes the job of one message of the job queue.
+func consumeJobMessage(ctx context.Context, body []byte) error {
+	var message interfaces.JobMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		// Delivering it again would not help
+		fmt.Println("invalid job message", string(body), err)
+		return nil
+	}
+	return ProcessJob(ctx, message.JobID)
+}
+
+// ProcessJob creates the tabloid of a queued job, recording the result of each page as it is uploaded.
+// Messages are delivered at least once, so jobs already ended are skipped, the job is claimed before it runs (see
+// ClaimJob, leased for JOB_LEASE_SECONDS, 600 by default) and it is marked as succeeded in the transaction that
+// creates the tabloid. A job running elsewhere is returned as an error, so its message is delivered again later.
+// A job rejected by the create use case (a 4xx, such as an invalid image) is marked as failed and is not retried.
+// Any other error, such as a database or S3 outage, is returned with the job queued again, so the message is
+// delivered again until the queue moves it to its dead-letter queue.
+// The staged file is deleted once the job ends.
+func ProcessJob(ctx context.Context, jobID string) error {
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	job, err := mysqlService.GetJob(jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Println("job not found", jobID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if job.Status == interfaces.JobStatusSucceeded || job.Status == interfaces.JobStatusFailed {
+		return nil
+	}
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return err
+	}
+	claimed, err := mysqlService.ClaimJob(job.ID, time.Duration(utils.GetEnvInt("JOB_LEASE_SECONDS", 600))*time.Second)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("job %s is running elsewhere", job.ID)
+	}
+
+	err = runJob(ctx, mysqlService, uploadService, job)
+	if err := endJobRun(mysqlService, job.ID, err); err != nil {
+		return err
+	}
+
+	if err := uploadService.DeleteObject(job.FileKey); err != nil {
+		fmt.Println("err de DeleteObject", job.FileKey, err)
+	}
+	return nil
+}
+
+// runJob creates the tabloid of a claimed job from its staged file.
+func runJob(ctx context.Context, mysqlService *mysqlservice.MysqlTabloideRepository,
+	uploadService *uploaderservice.UploaderAdapter, job *interfaces.Job) error {
+	var err error
+	job.Request.File, err = readStagedFile(ctx, uploadService, job)
+	if err != nil {
+		return err
+	}
+	defer utils.RemoveSpooledFiles(job.Request.File)
+
+	progress := &jobProgress{mysqlService: mysqlService, jobID: job.ID}
+	auditContext := interfaces.AuditContext{Username: job.Actor, RequestID: job.RequestID}
+	files := []interfaces.File{job.Request.File}
+	_, _, err = createTabloid(ctx, mysqlService, auditContext, job.Request, files, utils.UploadLimitsFromEnv(), createHooks{
+		onPagesRead: progress.start,
+		onPage:      progress.record,
+		onCommit: func(transaction *sql.Tx, tabloidID int64) error {
+			return mysqlService.CompleteJob(job.ID, tabloidID, transaction)
+		},
+	})
+	return err
+}
+
+// endJobRun releases a claimed job once its run ended with err. A job rejected with a 4xx is marked as failed.
+// Any other error queues the job again and is returned, so its message is delivered again.
+func endJobRun(mysqlService *mysqlservice.MysqlTabloideRepository, jobID string, err error) error {
+	if err == nil {
+		return nil
+	}
+	fmt.Println("err de runJob", jobID, err)
+	response := errorResponse(err)
+	if errorStatus(err) >= http.StatusInternalServerError {
+		if statusErr := mysqlService.ReleaseJob(jobID, interfaces.JobStatusQueued, response.Error, response.Code); statusErr != nil {
+			fmt.Println("err de ReleaseJob", jobID, statusErr)
+		}
+		return err
+	}
+	return mysqlService.ReleaseJob(jobID, interfaces.JobStatusFailed, response.Error, response.Code)
+}
+
+// jobProgress records the result of the pages of a job as they are uploaded.
+type jobProgress struct {
+	mysqlService *mysqlservice.MysqlTabloideRepository
+	jobID        string
+
+	mu    sync.Mutex
+	total int
+	pages []interfaces.JobPage
+}
+
+// start records the number of pages of the job.
+func (p *jobProgress) start(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	p.pages = []interfaces.JobPage{}
+	p.save()
+}
+
+// record records the result of one page. Pages end in any order; they are kept in page order.
+func (p *jobProgress) record(result interfaces.JobPage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pages = append(p.pages, result)
+	sort.Slice(p.pages, func(i, j int) bool { return p.pages[i].Order < p.pages[j].Order })
+	p.save()
+}
+
+// save writes the progress to the job. Failures are only logged: progress is informative.
+func (p *jobProgress) save() {
+	if err := p.mysqlService.UpdateJobPages(p.jobID, p.total, p.pages); err != nil {
+		fmt.Println("err de UpdateJobPages", p.jobID, err)
+	}
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	"test/lambda/utils"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockRepository returns a repository on a mocked connection, checking its expectations at the end of the test.
+func newMockRepository(t *testing.T) (*mysqlservice.MysqlTabloideRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		db.Close()
+	})
+	return mysqlservice.NewMysqlTabloideRepositoryWithDB(db), mock
+}
+
+func TestCreateTabloidErrorStatus(t *testing.T) {
+	t.Setenv("REGION", "us-east-1")
+	regionQuery := regexp.QuoteMeta("FROM regiao WHERE id = ?")
+
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		status int
+	}{
+		{
+			name: "database outage",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regionQuery).WillReturnError(errors.New("dial tcp: connection refused"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "unknown region",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regionQuery).WillReturnRows(sqlmock.NewRows([]string{"id", "nome", "dt_cadastro", "dt_alteracao"}))
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mysqlService, mock := newMockRepository(t)
+			test.expect(mock)
+
+			_, _, err := createTabloid(context.Background(), mysqlService, interfaces.AuditContext{},
+				interfaces.RequestEvent{RegionID: 144}, nil, utils.UploadLimitsFromEnv(), createHooks{})
+			if err == nil {
+				t.Fatal("createTabloid returned no error")
+			}
+			if status := errorStatus(err); status != test.status {
+				t.Errorf("createTabloid failed with status %d, expected %d", status, test.status)
+			}
+		})
+	}
+}
+
+func TestPageUploadErrorStatus(t *testing.T) {
+	if status := pageUploadErrorStatus(errors.New("operation error S3: PutObject, https response error StatusCode: 503")); status != http.StatusInternalServerError {
+		t.Errorf("pageUploadErrorStatus returned %d for an S3 failure, expected 500", status)
+	}
+	rejected := &utils.ImageValidationError{Code: utils.ErrorImageCorrupt, Message: "failed to decode image"}
+	if status := pageUploadErrorStatus(rejected); status != http.StatusBadRequest {
+		t.Errorf("pageUploadErrorStatus returned %d for a rejected image, expected 400", status)
+	}
+}
+
+func TestEndJobRunRequeuesTransientFailures(t *testing.T) {
+	releaseQuery := regexp.QuoteMeta("UPDATE job_tabloide SET status = ?")
+
+	t.Run("transient", func(t *testing.T) {
+		mysqlService, mock := newMockRepository(t)
+		mock.ExpectExec(releaseQuery).
+			WithArgs(interfaces.JobStatusQueued, "dial tcp: connection refused", "", "job-1", interfaces.JobStatusRunning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := endJobRun(mysqlService, "job-1", errors.New("dial tcp: connection refused"))
+		if err == nil {
+			t.Error("endJobRun returned no error, so the message would not be delivered again")
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		mysqlService, mock := newMockRepository(t)
+		mock.ExpectExec(releaseQuery).
+			WithArgs(interfaces.JobStatusFailed, "Region not found", "", "job-1", interfaces.JobStatusRunning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := endJobRun(mysqlService, "job-1", withStatus(http.StatusBadRequest, errors.New("Region not found"))); err != nil {
+			t.Errorf("endJobRun returned error: %v", err)
+		}
+	})
+}
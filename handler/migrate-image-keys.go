@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"path"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+
+	"github.com/google/uuid"
+)
+
+// KeyMigrationResult summarizes a run of MigrateImageKeys.
+type KeyMigrationResult struct {
+	Renamed map[string]string // New key of each migrated image, by old key.
+	Skipped []string          // Keys that could not be migrated, as the template needs a checksum they lack.
+}
+
+// MigrateImageKeys moves the stored page images whose keys do not follow the current key template to the layout
+// the template describes: each image, its resized copies and its original are copied to the new key, then the pages,
+// resized copies and versions referencing the old key are updated in one transaction per image.
+// Images shared by several pages take the key rendered for the first of them. Objects under the old keys are kept,
+// so the migration can be interrupted and run again; they can be deleted once the migration is checked.
+// When dryRun is set, nothing is copied or written.
+func MigrateImageKeys(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	dryRun bool) (*KeyMigrationResult, error) {
+	pages, err := mysqlService.GetPagesWithImageKey()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := mysqlService.GetAllTabloidVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &KeyMigrationResult{Renamed: map[string]string{}}
+	tabloids := map[int64]*interfaces.Tabloid{}
+	for _, page := range pages {
+		if _, renamed := result.Renamed[page.ImageKey]; renamed {
+			continue
+		}
+
+		tabloid, ok := tabloids[page.TabloidID]
+		if !ok {
+			tabloid, err = mysqlService.GetTabloidById(page.TabloidID)
+			if err != nil {
+				return result, err
+			}
+			tabloids[page.TabloidID] = tabloid
+		}
+
+		fields := utils.KeyFields{
+			TabloidID: tabloid.ID,
+			RegionID:  tabloid.RegiaoID,
+			StartDate: tabloid.DtInicioVigencia,
+			EndDate:   tabloid.DtFimVigencia,
+			Page:      page.Order + 1,
+			SHA256:    page.ChecksumSHA256,
+			Extension: path.Ext(page.ImageKey),
+		}
+		if uploadService.KeyTemplate.Matches(page.ImageKey, fields) {
+			continue
+		}
+		if fields.SHA256 == "" && uploadService.KeyTemplate.Uses("sha256") {
+			result.Skipped = append(result.Skipped, page.ImageKey)
+			continue
+		}
+		fields.UUID = uuid.New().String()
+		newKey := uploadService.KeyTemplate.Render(fields)
+
+		if !dryRun {
+			if err := moveImage(mysqlService, uploadService, page, newKey, versions); err != nil {
+				return result, err
+			}
+		}
+		result.Renamed[page.ImageKey] = newKey
+	}
+
+	return result, nil
+}
+
+// moveImage copies the image of the page to newKey and updates every reference to its old key.
+// versions are updated in place, so later images see the pages already rewritten.
+func moveImage(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	page interfaces.Page, newKey string, versions []interfaces.TabloidVersion) error {
+	variants := make([]uploaderservice.ImageVariant, 0, len(page.Variants))
+	for _, variant := range page.Variants {
+		if variant.Key != "" {
+			variants = append(variants, uploaderservice.ImageVariant{Width: variant.Width, Key: variant.Key})
+		}
+	}
+	copied, err := uploadService.CopyImage(page.ImageKey, newKey, variants)
+	if err != nil {
+		return err
+	}
+
+	newVariants := make([]interfaces.PageVariant, 0, len(copied))
+	for _, variant := range copied {
+		newVariants = append(newVariants, interfaces.PageVariant{Width: variant.Width, Key: variant.Key})
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	if err := mysqlService.RenameImageKey(page.ImageKey, newKey, newVariants, transaction); err != nil {
+		return err
+	}
+	for i := range versions {
+		changed := false
+		for j, versionPage := range versions[i].Pages {
+			if versionPage == page.ImageKey {
+				versions[i].Pages[j] = newKey
+				changed = true
+			}
+		}
+		if changed {
+			if err := mysqlService.UpdateTabloidVersionPages(versions[i].ID, versions[i].Pages, transaction); err != nil {
+				return err
+			}
+		}
+	}
+
+	return transaction.Commit()
+}
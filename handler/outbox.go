@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"test/lambda/interfaces"
+	eventsinkservice "test/lambda/services/event-sink-service"
+	mysqlservice "test/lambda/services/mysql-service"
+	webhookservice "test/lambda/services/webhook-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DispatchResult summarizes a run of the event dispatcher.
+type DispatchResult struct {
+	Published int `json:"publicados"` // Validity events written to the outbox (TabloidPublished, TabloidExpired).
+	Sent      int `json:"enviados"`   // Events delivered to the sink.
+	Failed    int `json:"falhas"`     // Events whose delivery failed, retried on the next run.
+
+	WebhookDeliveries int64          `json:"entregas_webhook"`   // Webhook deliveries created for the events.
+	Webhooks          *WebhookResult `json:"webhooks,omitempty"` // Result of the webhook deliveries attempted.
+}
+
+// recordEvent writes a domain event about a tabloid to the outbox, inside the transaction of the change it describes.
+func recordEvent(mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	eventType string, tabloid interfaces.Tabloid, pages []string, discriminator string) error {
+	event, err := utils.NewDomainEvent(eventType, tabloid, pages, discriminator)
+	if err != nil {
+		return err
+	}
+	return mysqlService.InsertOutboxEvent(event, transaction)
+}
+
+// RecordValidityEvents writes TabloidPublished for the tabloids whose validity started and TabloidExpired for those
+// whose validity ended (the whole last day included) within lookback before now. Their deduplication IDs carry the
+// date, so overlapping runs write each event once, and a tabloid whose dates change is published again.
+func RecordValidityEvents(mysqlService *mysqlservice.MysqlTabloideRepository, now time.Time, lookback time.Duration) (int, error) {
+	started, err := mysqlService.GetTabloidsStartedBetween(now.Add(-lookback), now)
+	if err != nil {
+		return 0, err
+	}
+	today := now.Truncate(24 * time.Hour)
+	ended, err := mysqlService.GetTabloidsEndedBetween(today.Add(-lookback), today.Add(-time.Second))
+	if err != nil {
+		return 0, err
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer transaction.Rollback()
+
+	for _, tabloid := range started {
+		if err := recordEvent(mysqlService, transaction, interfaces.EventTabloidPublished, tabloid, nil, tabloid.DtInicioVigencia.Format("20060102")); err != nil {
+			return 0, err
+		}
+	}
+	for _, tabloid := range ended {
+		if err := recordEvent(mysqlService, transaction, interfaces.EventTabloidExpired, tabloid, nil, tabloid.DtFimVigencia.Format("20060102")); err != nil {
+			return 0, err
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		return 0, err
+	}
+	return len(started) + len(ended), nil
+}
+
+// DispatchOutbox delivers up to limit pending events of the outbox to the sink, oldest first.
+// The events are claimed for lease in a first transaction, which also creates their pending deliveries to the
+// webhooks subscribed to them (attempted by DeliverWebhooks), then published outside of any transaction, and their
+// results are recorded in a second one. Events are marked as sent only after the sink accepts them, so delivery is at
+// least once: consumers drop duplicates by their deduplication ID, such as the events published again once their
+// lease expires because their results could not be recorded. Failed events are retried on later runs, up to
+// maxAttempts attempts. Concurrent dispatchers skip each other's events.
+func DispatchOutbox(ctx context.Context, mysqlService *mysqlservice.MysqlTabloideRepository, sink eventsinkservice.EventSink,
+	limit, maxAttempts int, lease time.Duration) (*DispatchResult, error) {
+	result := &DispatchResult{}
+	pending, err := claimOutboxEvents(mysqlService, limit, maxAttempts, lease, result)
+	if err != nil {
+		return nil, err
+	}
+
+	publishErrors := make([]error, len(pending))
+	for i, event := range pending {
+		publishErrors[i] = sink.Publish(ctx, event)
+	}
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	for i, event := range pending {
+		if publishErrors[i] != nil {
+			if err := mysqlService.MarkEventFailed(event.ID, publishErrors[i].Error(), transaction); err != nil {
+				return nil, err
+			}
+			result.Failed++
+			continue
+		}
+		if err := mysqlService.MarkEventSent(event.ID, transaction); err != nil {
+			return nil, err
+		}
+		result.Sent++
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// claimOutboxEvents claims the pending events for lease, creates their webhook deliveries, counted in result, and
+// commits the claim.
+func claimOutboxEvents(mysqlService *mysqlservice.MysqlTabloideRepository, limit, maxAttempts int, lease time.Duration,
+	result *DispatchResult) ([]interfaces.DomainEvent, error) {
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	pending, err := mysqlService.ClaimPendingEvents(limit, maxAttempts, lease, transaction)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range pending {
+		deliveries, err := mysqlService.InsertWebhookDeliveries(event, transaction)
+		if err != nil {
+			return nil, err
+		}
+		result.WebhookDeliveries += deliveries
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// HandleDispatchEventsEvent handles the scheduled EventBridge event that writes the validity events of the last
+// EVENTS_LOOKBACK_HOURS hours (48 by default), delivers the pending events of the outbox to EVENT_SINK,
+// OUTBOX_BATCH_SIZE at a time (100 by default), giving up on an event after OUTBOX_MAX_ATTEMPTS failures (10) and
+// leasing the events for OUTBOX_LEASE_SECONDS (600, more than the timeout of the function), and attempts the webhook deliveries that are due
+// (see WebhookPolicyFromEnv).
+func HandleDispatchEventsEvent(ctx context.Context, event events.CloudWatchEvent) (*DispatchResult, error) {
+	sink, err := eventsinkservice.NewEventSink()
+	if err != nil {
+		return nil, err
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	lookback := time.Duration(utils.GetEnvInt("EVENTS_LOOKBACK_HOURS", 48)) * time.Hour
+	published, err := RecordValidityEvents(mysqlService, time.Now(), lookback)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := DispatchOutbox(ctx, mysqlService, sink, utils.GetEnvInt("OUTBOX_BATCH_SIZE", 100), utils.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+		time.Duration(utils.GetEnvInt("OUTBOX_LEASE_SECONDS", 600))*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	result.Published = published
+
+	result.Webhooks, err = DeliverWebhooks(ctx, mysqlService, webhookservice.NewWebhookClient(), WebhookPolicyFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("dispatch-events: %d validity events, %d sent, %d failed; webhooks: %d delivered, %d retried, %d dead\n",
+		result.Published, result.Sent, result.Failed, result.Webhooks.Delivered, result.Webhooks.Retried, result.Webhooks.Dead)
+	return result, nil
+}
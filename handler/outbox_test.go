@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"test/lambda/interfaces"
+	eventsinkservice "test/lambda/services/event-sink-service"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// failingSink rejects every event, as an unreachable topic would.
+type failingSink struct{}
+
+func (failingSink) Publish(ctx context.Context, event interfaces.DomainEvent) error {
+	return errors.New("operation error SNS: Publish, https response error StatusCode: 503")
+}
+
+// expectOutboxClaim expects DispatchOutbox to claim event 7 for ten minutes and create its webhook deliveries.
+func expectOutboxClaim(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM outbox_evento")).WithArgs(10, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id_deduplicacao", "tipo", "tabloide_id", "regiao_id", "payload", "tentativas", "dt_cadastro"}).
+			AddRow(7, "TabloidPublished:42:20261019", interfaces.EventTabloidPublished, 42, 144, `{"id":42}`, 0, "2026-10-19 03:00:00"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox_evento SET dt_lease = DATE_ADD(NOW(), INTERVAL ? SECOND)")).WithArgs(600, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO webhook_entrega")).
+		WithArgs(7, interfaces.WebhookDeliveryPending, 144, interfaces.EventTabloidPublished).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+}
+
+func TestDispatchOutboxRedeliversAfterLeaseExpiry(t *testing.T) {
+	mysqlService, mock := newMockRepository(t)
+	sink := &eventsinkservice.MemorySink{}
+	sentQuery := regexp.QuoteMeta("SET dt_envio = NOW()")
+
+	// The event is published, but the run stops before recording it: the event stays leased, not sent
+	expectOutboxClaim(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(sentQuery).WithArgs(7).WillReturnError(errors.New("connection reset by peer"))
+	mock.ExpectRollback()
+	if _, err := DispatchOutbox(context.Background(), mysqlService, sink, 100, 10, 10*time.Minute); err == nil {
+		t.Fatal("DispatchOutbox returned no error though the event could not be marked as sent")
+	}
+
+	// Once the lease expires, the next run claims and publishes the event again
+	expectOutboxClaim(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(sentQuery).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	result, err := DispatchOutbox(context.Background(), mysqlService, sink, 100, 10, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("DispatchOutbox returned error: %v", err)
+	}
+	if result.Sent != 1 || result.Failed != 0 || result.WebhookDeliveries != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The sink drops the duplicate by its deduplication ID, as consumers do
+	if events := sink.Events(); len(events) != 1 || events[0].DeduplicationID != "TabloidPublished:42:20261019" {
+		t.Errorf("sink kept %+v, expected the event once", events)
+	}
+}
+
+func TestDispatchOutboxRecordsFailures(t *testing.T) {
+	mysqlService, mock := newMockRepository(t)
+
+	expectOutboxClaim(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SET tentativas = tentativas + 1, dt_lease = NULL, ultimo_erro = ?")).
+		WithArgs("operation error SNS: Publish, https response error StatusCode: 503", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := DispatchOutbox(context.Background(), mysqlService, failingSink{}, 100, 10, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("DispatchOutbox returned error: %v", err)
+	}
+	if result.Sent != 0 || result.Failed != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
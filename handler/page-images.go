@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+)
+
+// readPageImages reads the uploaded files as the list of page images of a tabloid, in page order.
+// A ZIP archive, kept in its temporary file, is expanded into one page per entry, each bounded by
+// limits.MaxPageBytes; any other file is a single page.
+func readPageImages(files []interfaces.File, uploadService *uploaderservice.UploaderAdapter, limits utils.UploadLimits) ([][]byte, error) {
+	var images [][]byte
+	for _, file := range files {
+		if file.Path == "" {
+			images = append(images, file.Content)
+			continue
+		}
+
+		zipPages, err := expandZipFile(file, uploadService, limits)
+		if err != nil {
+			return nil, err
+		}
+		for _, zipPage := range zipPages {
+			images = append(images, zipPage.Data)
+		}
+	}
+	return images, nil
+}
+
+// expandZipFile expands the ZIP archive kept in the temporary file of file into its pages.
+func expandZipFile(file interfaces.File, uploadService *uploaderservice.UploaderAdapter, limits utils.UploadLimits) ([]utils.ZipPage, error) {
+	archive, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	zipLimits := utils.ZipLimitsFromEnv()
+	zipLimits.MaxEntryBytes = min(zipLimits.MaxEntryBytes, limits.MaxPageBytes)
+	return utils.ExpandZipPagesAt(archive, file.Size, zipLimits, uploadService.ValidateImage)
+}
+
+// imageVariantWidths reads the widths of the resized copies generated for each page from IMAGE_VARIANT_WIDTHS,
+// narrowest first, whatever order they are listed in.
+func imageVariantWidths() ([]int, error) {
+	widths := os.Getenv("IMAGE_VARIANT_WIDTHS")
+	if widths == "" {
+		widths = "200,600,1200"
+	}
+	parsed, err := utils.ParseIntList(widths)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(parsed)
+	return parsed, nil
+}
+
+// uploadedPage is a page image stored in S3 by uploadPages, not yet recorded in the database.
+type uploadedPage struct {
+	Image    *uploaderservice.UploadedImage
+	Variants []uploaderservice.ImageVariant
+}
+
+// uploadConcurrency reads how many pages are uploaded at a time from UPLOAD_CONCURRENCY.
+func uploadConcurrency() int {
+	return max(utils.GetEnvInt("UPLOAD_CONCURRENCY", 4), 1)
+}
+
+// uploadPages stores the page images of a tabloid and their resized copies in S3, running at most
+// UPLOAD_CONCURRENCY uploads at a time. The result is in page order, whatever order the uploads finish in.
+// The key, size and duration of each page are logged as it ends. onPage, when not nil, receives the result of each
+// page as it ends, from the uploading goroutines.
+// The first failure cancels the pending uploads and the error is returned along with the pages already stored, for
+// the caller to delete with deleteUploadedPages.
+func uploadPages(ctx context.Context, uploadService *uploaderservice.UploaderAdapter, images [][]byte,
+	tabloid interfaces.Tabloid, uploader string, widths []int, onPage func(interfaces.JobPage)) ([]uploadedPage, error) {
+	pages := make([]uploadedPage, len(images))
+	err := utils.RunBounded(ctx, len(images), uploadConcurrency(), func(ctx context.Context, order int) error {
+		start := time.Now()
+		ref := uploaderservice.PageRef{Tabloid: tabloid, Order: order, Uploader: uploader}
+		uploadedImage, err := uploadService.UploadImage(ctx, images[order], ref)
+		if err == nil {
+			pages[order].Image = uploadedImage
+			pages[order].Variants, err = uploadService.UploadImageVariants(ctx, uploadedImage.Content, uploadedImage.Key, ref, widths)
+		}
+
+		result := pageResult(order, pages[order], time.Since(start), err)
+		fmt.Printf("upload tabloid=%d page=%d key=%s bytes=%d variants=%d duration=%dms status=%s\n",
+			tabloid.ID, order, result.ImageKey, len(images[order]), result.Variants, result.DurationMs, result.Status)
+		if onPage != nil {
+			onPage(result)
+		}
+		return err
+	})
+	return pages, err
+}
+
+// pageResult describes how the upload of a page ended, as reported by jobs.
+func pageResult(order int, page uploadedPage, duration time.Duration, err error) interfaces.JobPage {
+	result := interfaces.JobPage{
+		Order:      order,
+		Status:     interfaces.JobPageStatusUploaded,
+		Variants:   len(page.Variants),
+		DurationMs: duration.Milliseconds(),
+	}
+	if page.Image != nil {
+		result.ImageKey = page.Image.Key
+	}
+	if err != nil {
+		response := errorResponse(err)
+		result.Status = interfaces.JobPageStatusFailed
+		result.Error = response.Error
+		result.Code = response.Code
+	}
+	return result
+}
+
+// deleteUploadedPages deletes the pages stored by a failed upload, along with their resized copies. It must run once
+// the transaction of the upload is rolled back.
+// Deduplicated pages are kept, as the object is shared with pages stored before. With content-addressed keys, a
+// concurrent upload of the same image may also have missed the object and committed a page using it, so those
+// objects are only deleted when no committed page references them.
+// Failures are only logged: the reconciliation removes whatever is left behind.
+func deleteUploadedPages(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	pages []uploadedPage) {
+	for _, page := range pages {
+		if page.Image == nil || page.Image.Deduplicated {
+			continue
+		}
+		if uploaderservice.ContentAddressed() {
+			referenced, err := mysqlService.IsStoredImageReferenced(page.Image.Key)
+			if err != nil {
+				fmt.Println("err de IsStoredImageReferenced", page.Image.Key, err)
+				continue
+			}
+			if referenced {
+				continue
+			}
+		}
+		variantKeys := make([]string, 0, len(page.Variants))
+		for _, variant := range page.Variants {
+			variantKeys = append(variantKeys, variant.Key)
+		}
+		if err := uploadService.DeleteImage(page.Image.Key, variantKeys); err != nil {
+			fmt.Println("err de DeleteImage", page.Image.Key, err)
+		}
+	}
+}
+
+// insertPages records the uploaded pages of a tabloid and their resized copies in the database, in page order.
+// It returns the inserted pages and their keys.
+func insertPages(mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx, tabloidID int64,
+	uploaded []uploadedPage) ([]interfaces.Page, []string, error) {
+	pages := make([]interfaces.Page, 0, len(uploaded))
+	pageKeys := make([]string, 0, len(uploaded))
+	for order, uploadedPage := range uploaded {
+		// The URL of the page is built when it is read
+		page := interfaces.Page{
+			TabloidID:      tabloidID,
+			ImageKey:       uploadedPage.Image.Key,
+			StorageID:      storageID(),
+			Order:          order,
+			ChecksumSHA256: uploadedPage.Image.ChecksumSHA256,
+		}
+		if err := mysqlService.InsertTabloidImage(page, transaction); err != nil {
+			return nil, nil, err
+		}
+
+		for _, variant := range uploadedPage.Variants {
+			pageVariant := interfaces.PageVariant{Width: variant.Width, Key: variant.Key}
+			if err := mysqlService.InsertImageVariant(page.ImageKey, pageVariant, transaction); err != nil {
+				return nil, nil, err
+			}
+			page.Variants = append(page.Variants, pageVariant)
+		}
+
+		pages = append(pages, page)
+		pageKeys = append(pageKeys, page.ImageKey)
+	}
+	return pages, pageKeys, nil
+}
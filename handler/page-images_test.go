@@ -0,0 +1,17 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImageVariantWidthsNarrowestFirst(t *testing.T) {
+	t.Setenv("IMAGE_VARIANT_WIDTHS", "1200,200,600")
+	widths, err := imageVariantWidths()
+	if err != nil {
+		t.Fatalf("imageVariantWidths returned error: %v", err)
+	}
+	if !reflect.DeepEqual(widths, []int{200, 600, 1200}) {
+		t.Errorf("imageVariantWidths returned %v, expected [200 600 1200]", widths)
+	}
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"os"
+	"test/lambda/interfaces"
+	urlbuilderservice "test/lambda/services/url-builder-service"
+	"test/lambda/utils"
+)
+
+// resolvePageURLs builds the URLs of the pages of a tabloid, and of their resized copies, from their object keys.
+// Signed URLs expire by the end of the validity of the tabloid at the latest.
+// Pages not converted by the backfill yet keep their stored URL; archived pages are not served and get no URL.
+func resolvePageURLs(urlBuilder urlbuilderservice.URLBuilder, pages []interfaces.Page, tabloid interfaces.Tabloid) error {
+	for i := range pages {
+		page := &pages[i]
+		if page.StorageID == interfaces.StorageIDArchive {
+			continue
+		}
+		if page.ImageKey != "" {
+			imageURL, err := urlBuilder.BuildURL(objectRef(page, page.ImageKey, tabloid))
+			if err != nil {
+				return err
+			}
+			page.ImageURL = imageURL
+		}
+
+		for j := range page.Variants {
+			variant := &page.Variants[j]
+			if variant.Key == "" {
+				continue
+			}
+			variantURL, err := urlBuilder.BuildURL(objectRef(page, variant.Key, tabloid))
+			if err != nil {
+				return err
+			}
+			variant.ImageURL = variantURL
+		}
+	}
+	return nil
+}
+
+// objectRef identifies an object of the given page of a tabloid for the URL builder.
+func objectRef(page *interfaces.Page, key string, tabloid interfaces.Tabloid) urlbuilderservice.ObjectRef {
+	return urlbuilderservice.ObjectRef{
+		StorageID: page.StorageID,
+		Key:       key,
+		RegionID:  tabloid.RegiaoID,
+		NotAfter:  utils.ValidityEnd(tabloid.DtFimVigencia),
+	}
+}
+
+// storageID returns the ID of the storage backend new page images are uploaded to, from STORAGE_ID.
+func storageID() string {
+	if id := os.Getenv("STORAGE_ID"); id != "" {
+		return id
+	}
+	return "s3"
+}
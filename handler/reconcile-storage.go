@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ReconcileOptions configures a run of ReconcileStorage.
+type ReconcileOptions struct {
+	Prefixes         []string      // Key prefixes listed and compared with the database.
+	Grace            time.Duration // Minimum age of an unreferenced object before it is treated as an orphan.
+	Action           string        // One of the interfaces.ReconcileAction* constants.
+	QuarantinePrefix string        // Prefix orphans are moved under by the quarantine action.
+}
+
+// ReconcileOptionsFromEnv reads the reconciliation options: RECONCILE_PREFIXES (comma-separated, "RPA/v3/" by
+// default, plus IMAGE_ORIGINALS_PREFIX when set, listed in the originals bucket), RECONCILE_GRACE_HOURS (24),
+// RECONCILE_ACTION (report) and RECONCILE_QUARANTINE_PREFIX (quarantine/).
+func ReconcileOptionsFromEnv() ReconcileOptions {
+	options := ReconcileOptions{
+		Grace:            time.Duration(utils.GetEnvInt("RECONCILE_GRACE_HOURS", 24)) * time.Hour,
+		Action:           os.Getenv("RECONCILE_ACTION"),
+		QuarantinePrefix: os.Getenv("RECONCILE_QUARANTINE_PREFIX"),
+	}
+	for _, prefix := range strings.Split(os.Getenv("RECONCILE_PREFIXES"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			options.Prefixes = append(options.Prefixes, prefix)
+		}
+	}
+	if len(options.Prefixes) == 0 {
+		options.Prefixes = []string{"RPA/v3/"}
+	}
+	if originals := os.Getenv("IMAGE_ORIGINALS_PREFIX"); originals != "" {
+		options.Prefixes = append(options.Prefixes, originals)
+	}
+	if options.Action == "" {
+		options.Action = interfaces.ReconcileActionReport
+	}
+	if options.QuarantinePrefix == "" {
+		options.QuarantinePrefix = "quarantine/"
+	}
+	return options
+}
+
+// ReconcileStorage lists the objects under the configured prefixes and compares them with the keys referenced by
+// pages, resized copies and versions (and the originals of those pages and of archived pages). Orphans older than the grace period are
+// reported and, depending on the action, quarantined or deleted; keys referenced but never written are reported.
+// Each orphan is checked again right before it is acted on, as an upload may have reused its content-addressed key
+// since the references were listed.
+// It returns the report, with the orphans processed so far when an action fails.
+func ReconcileStorage(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	options ReconcileOptions, now time.Time) (*interfaces.ReconciliationReport, error) {
+	switch options.Action {
+	case interfaces.ReconcileActionReport, interfaces.ReconcileActionDelete:
+	case interfaces.ReconcileActionQuarantine:
+		for _, prefix := range options.Prefixes {
+			if strings.HasPrefix(options.QuarantinePrefix, prefix) {
+				return nil, fmt.Errorf("quarantine prefix %s must not be under the reconciled prefix %s", options.QuarantinePrefix, prefix)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid reconcile action: %s", options.Action)
+	}
+
+	referenced, err := referencedImageKeys(mysqlService)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []interfaces.StoredObject
+	for _, prefix := range options.Prefixes {
+		listed, err := uploadService.ListObjects(prefix)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, listed...)
+	}
+
+	// Originals keep the extension of the upload, so they are matched by the key of their page without extension.
+	// Pages of archived tabloids keep their originals too, as they may be restored.
+	if originals := os.Getenv("IMAGE_ORIGINALS_PREFIX"); originals != "" {
+		archived, err := mysqlService.GetArchivedImageKeys()
+		if err != nil {
+			return nil, err
+		}
+		pageBases := map[string]bool{}
+		for _, keys := range []map[string]bool{referenced, archived} {
+			for key := range keys {
+				pageBases[strings.TrimSuffix(key, path.Ext(key))] = true
+			}
+		}
+		for _, object := range objects {
+			original := strings.TrimPrefix(object.Key, originals)
+			if original != object.Key && pageBases[strings.TrimSuffix(original, path.Ext(original))] {
+				referenced[object.Key] = true
+			}
+		}
+	}
+
+	report := utils.ReconcileObjects(objects, referenced, options.Prefixes, now, options.Grace)
+	report.Action = options.Action
+	if options.Action == interfaces.ReconcileActionReport {
+		return &report, nil
+	}
+
+	for _, orphan := range report.Orphans {
+		unused, err := isOrphanStillUnused(mysqlService, uploadService, orphan.Key, options.Grace, now)
+		if err != nil {
+			return &report, err
+		}
+		if !unused {
+			fmt.Println("reconcile: orphan reused since the listing, skipped", orphan.Key)
+			continue
+		}
+
+		if options.Action == interfaces.ReconcileActionQuarantine {
+			err = uploadService.QuarantineObject(orphan.Key, options.QuarantinePrefix)
+		} else {
+			err = uploadService.DeleteObject(orphan.Key)
+		}
+		if err != nil {
+			return &report, err
+		}
+		report.Processed = append(report.Processed, orphan.Key)
+	}
+	return &report, nil
+}
+
+// isOrphanStillUnused checks an orphan again: it must still be older than the grace period, which an upload reusing
+// the object resets, and no committed page or resized copy may reference it.
+func isOrphanStillUnused(mysqlService *mysqlservice.MysqlTabloideRepository, uploadService *uploaderservice.UploaderAdapter,
+	key string, grace time.Duration, now time.Time) (bool, error) {
+	lastModified, exists, err := uploadService.ObjectLastModified(key)
+	if err != nil {
+		return false, err
+	}
+	if !exists || lastModified.After(now.Add(-grace)) {
+		return false, nil
+	}
+
+	var referenced bool
+	if originals := os.Getenv("IMAGE_ORIGINALS_PREFIX"); originals != "" && strings.HasPrefix(key, originals) {
+		original := strings.TrimPrefix(key, originals)
+		referenced, err = mysqlService.IsPageBaseReferenced(strings.TrimSuffix(original, path.Ext(original)))
+	} else {
+		referenced, err = mysqlService.IsStoredImageReferenced(key)
+	}
+	return !referenced, err
+}
+
+// referencedImageKeys returns the keys of every object of the page bucket referenced by pages, resized copies and
+// versions. URLs of rows not converted by the backfill yet are turned into keys. Images of archived pages are kept
+// in the archive, so versions showing them do not reference the page bucket.
+func referencedImageKeys(mysqlService *mysqlservice.MysqlTabloideRepository) (map[string]bool, error) {
+	references, err := mysqlService.GetStoredImageReferences()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := mysqlService.GetAllTabloidVersions()
+	if err != nil {
+		return nil, err
+	}
+	archived, err := mysqlService.GetArchivedImageKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, reference := range references {
+		referenced[imageKeyFromURL(reference)] = true
+	}
+	for _, version := range versions {
+		for _, page := range version.Pages {
+			if key := imageKeyFromURL(page); !archived[key] {
+				referenced[key] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// HandleReconcileStorageEvent handles the scheduled EventBridge event that runs the reconciliation with the
+// options of the environment. The report is logged as JSON and returned.
+func HandleReconcileStorageEvent(ctx context.Context, event events.CloudWatchEvent) (*interfaces.ReconciliationReport, error) {
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := ReconcileStorage(mysqlservice.NewMysqlTabloideRepository(), uploadService, ReconcileOptionsFromEnv(), time.Now().UTC())
+	if report != nil {
+		if encoded, marshalErr := json.Marshal(report); marshalErr == nil {
+			fmt.Println(string(encoded))
+		}
+	}
+	return report, err
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	urlbuilderservice "test/lambda/services/url-builder-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleRegionFeedRequest handles the public GET request for the currently valid, active tabloids of a region.
+// The response carries a strong ETag computed from its content and the last dt_alteracao, answers
+// If-None-Match with 304 and is cacheable until the nearest validity boundary, capped by FEED_CACHE_MAX_AGE.
+func HandleRegionFeedRequest(c *gin.Context) {
+	handleRegionDocument(c, utils.IsTabloidCurrent, func(feed interfaces.RegionFeed, now time.Time) (string, []byte, error) {
+		body, err := json.Marshal(feed)
+		return "application/json; charset=utf-8", body, err
+	})
+}
+
+// HandleRegionAtomRequest handles the public GET request for the Atom feed of a region.
+// Each current or upcoming tabloid becomes one entry linking its page images.
+func HandleRegionAtomRequest(c *gin.Context) {
+	handleRegionDocument(c, isTabloidScheduled, func(feed interfaces.RegionFeed, now time.Time) (string, []byte, error) {
+		scheme := "https"
+		if c.Request.TLS == nil && c.GetHeader("X-Forwarded-Proto") == "" {
+			scheme = "http"
+		}
+		body, err := utils.BuildAtomFeed(feed, fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, c.Request.URL.Path))
+		return "application/atom+xml; charset=utf-8", body, err
+	})
+}
+
+// HandleRegionCalendarRequest handles the public GET request for the iCalendar feed of a region.
+// Each current or upcoming tabloid becomes one all-day event spanning its validity.
+func HandleRegionCalendarRequest(c *gin.Context) {
+	handleRegionDocument(c, isTabloidScheduled, func(feed interfaces.RegionFeed, now time.Time) (string, []byte, error) {
+		return "text/calendar; charset=utf-8", utils.BuildICalendar(feed, now), nil
+	})
+}
+
+// handleRegionDocument loads the tabloids of the :id region accepted by include, renders them and writes the
+// document with ETag, Last-Modified and Cache-Control headers, answering If-None-Match with 304 and unknown regions with 404.
+func handleRegionDocument(c *gin.Context, include func(interfaces.Tabloid, time.Time) bool,
+	render func(interfaces.RegionFeed, time.Time) (string, []byte, error)) {
+	regionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || regionID < 1 {
+		c.JSON(http.StatusBadRequest, Response{Error: "invalid region id: " + c.Param("id")})
+		return
+	}
+
+	now := time.Now().UTC()
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	// Unknown regions are not cached like empty feeds, so a mistyped ID does not linger in the CDN
+	exists, err := mysqlService.RegionExists(regionID)
+	if err != nil {
+		fmt.Println("err de RegionExists", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, Response{Error: "Region not found"})
+		return
+	}
+	feed, tabloids, err := loadRegionFeed(mysqlService, regionID, now, include)
+	if err != nil {
+		fmt.Println("err de loadRegionFeed", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	contentType, body, err := render(feed, now)
+	if err != nil {
+		fmt.Println("err de render", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	lastModified := utils.FeedLastModified(feed)
+	etag := utils.ComputeETag(body, lastModified)
+	boundary, hasBoundary := utils.NextValidityBoundary(tabloids, now)
+	maxAge := utils.CacheControlMaxAge(now, boundary, hasBoundary, utils.GetEnvInt("FEED_CACHE_MAX_AGE", 300))
+	if urlbuilderservice.PrivateMode() {
+		// Cached copies must leave clients time to fetch the signed URLs they carry
+		maxAge = min(maxAge, int(urlbuilderservice.URLExpiry()/time.Second)/2)
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if utils.ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// loadRegionFeed builds the feed of a region with the tabloids accepted by include at the given instant,
+// resolving the page URLs from their keys. In private mode the pages of tabloids not valid yet are withheld.
+// It also returns every active tabloid that has not expired yet, including future ones, to compute validity boundaries.
+func loadRegionFeed(mysqlService *mysqlservice.MysqlTabloideRepository, regionID int, now time.Time,
+	include func(interfaces.Tabloid, time.Time) bool) (interfaces.RegionFeed, []interfaces.Tabloid, error) {
+	feed := interfaces.RegionFeed{RegionID: regionID, Tabloids: []interfaces.FeedTabloid{}}
+
+	tabloids, err := mysqlService.GetActiveTabloidsByRegion(regionID, now.Truncate(24*time.Hour).AddDate(0, 0, -1))
+	if err != nil {
+		return feed, nil, err
+	}
+
+	var includedIDs []int64
+	for _, tabloid := range tabloids {
+		if include(tabloid, now) {
+			includedIDs = append(includedIDs, tabloid.ID)
+		}
+	}
+	pagesByTabloid, err := mysqlService.GetPagesByTabloidIds(includedIDs)
+	if err != nil {
+		return feed, nil, err
+	}
+	urlBuilder, err := urlbuilderservice.NewURLBuilder()
+	if err != nil {
+		return feed, nil, err
+	}
+
+	for _, tabloid := range tabloids {
+		if !include(tabloid, now) {
+			continue
+		}
+		pages := pagesByTabloid[tabloid.ID]
+		if pages == nil {
+			pages = []interfaces.Page{}
+		}
+		if urlbuilderservice.PrivateMode() && now.Before(tabloid.DtInicioVigencia) {
+			// Pages under embargo are withheld until the tabloid is valid
+			pages = []interfaces.Page{}
+		} else if err := resolvePageURLs(urlBuilder, pages, tabloid); err != nil {
+			return feed, nil, err
+		}
+		feed.Tabloids = append(feed.Tabloids, interfaces.FeedTabloid{Tabloid: tabloid, Pages: pages})
+	}
+
+	return feed, tabloids, nil
+}
+
+// isTabloidScheduled reports whether the tabloid is active and has not expired yet, including tabloids not valid yet.
+func isTabloidScheduled(tabloid interfaces.Tabloid, now time.Time) bool {
+	return tabloid.Ativo && now.Before(utils.ValidityEnd(tabloid.DtFimVigencia))
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	"test/lambda/utils"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandleTabloidQueueEvent handles the SQS events of the tabloid queue, where upstream systems such as the RPA robot
+// send the tabloids to create instead of calling the HTTP API. Each message is a TabloidManifest whose files are keys
+// of the bucket, and runs the same create use case as POST /test.
+// Failed messages are reported in BatchItemFailures, so only they are delivered again (and, after the maxReceiveCount
+// of the queue, moved to its dead-letter queue) while the rest of the batch is deleted.
+// SQS delivers at least once: the message ID is the idempotency key of the create, so a message delivered again
+// after its tabloid was created is skipped.
+func HandleTabloidQueueEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	response := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		// Nothing can be processed: the whole batch is delivered again
+		return response, err
+	}
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+
+	for _, record := range event.Records {
+		idempotencyKey := "tabloid-queue:" + record.MessageId
+		tabloidID, err := mysqlService.GetProcessedRequest(idempotencyKey)
+		if err == nil {
+			fmt.Printf("tabloid queue: message %s already created tabloid %d\n", record.MessageId, tabloidID)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("err de GetProcessedRequest", record.MessageId, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+			continue
+		}
+
+		auditContext := interfaces.AuditContext{Username: "tabloid-queue", RequestID: record.MessageId}
+		tabloid, err := createFromMessage(ctx, auditContext, mysqlService, uploadService, idempotencyKey, record.Body)
+		if err != nil {
+			fmt.Println("err de createFromMessage", record.MessageId, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+			continue
+		}
+		fmt.Printf("tabloid queue: message %s created tabloid %d\n", record.MessageId, tabloid.ID)
+	}
+	return response, nil
+}
+
+// createFromMessage creates the tabloid described by the TabloidManifest in the body of a message of the tabloid queue.
+func createFromMessage(ctx context.Context, auditContext interfaces.AuditContext, mysqlService *mysqlservice.MysqlTabloideRepository,
+	uploadService *uploaderservice.UploaderAdapter, idempotencyKey, body string) (*interfaces.Tabloid, error) {
+	manifest, err := utils.ParseTabloidManifest([]byte(body))
+	if err != nil {
+		return nil, withStatus(http.StatusBadRequest, err)
+	}
+	return createFromManifest(ctx, auditContext, mysqlService, idempotencyKey, *manifest, func(ctx context.Context, name string) (io.ReadCloser, error) {
+		return uploadService.OpenObject(ctx, name)
+	})
+}
+
+// createFromManifest reads the files of a manifest with openFile and creates its tabloid through the create use case.
+// The files are bounded and kept like an upload request: ZIP archives in temporary files, removed afterwards.
+// idempotencyKey, when set, is recorded in the transaction of the create, for GetProcessedRequest.
+// Like createTabloid, its error carries a 4xx when the manifest is rejected and a 5xx when it is worth retrying.
+func createFromManifest(ctx context.Context, auditContext interfaces.AuditContext, mysqlService *mysqlservice.MysqlTabloideRepository,
+	idempotencyKey string, manifest interfaces.TabloidManifest, openFile func(ctx context.Context, name string) (io.ReadCloser, error)) (*interfaces.Tabloid, error) {
+	limits := utils.UploadLimitsFromEnv()
+	files := make([]interfaces.File, 0, len(manifest.Files))
+	defer func() { utils.RemoveSpooledFiles(files...) }()
+	var total int64
+	for _, name := range manifest.Files {
+		file, err := readManifestFile(ctx, openFile, name, limits)
+		if err != nil {
+			return nil, withStatus(manifestFileErrorStatus(err), err)
+		}
+		files = append(files, file)
+		if total += file.Size; total > limits.MaxRequestBytes {
+			return nil, withStatus(http.StatusRequestEntityTooLarge, &utils.UploadTooLargeError{Limit: limits.MaxRequestBytes, What: "request"})
+		}
+	}
+
+	event, err := utils.ManifestRequestEvent(manifest, files)
+	if err != nil {
+		return nil, withStatus(http.StatusBadRequest, err)
+	}
+	if err := utils.ValidateStruct(event); err != nil {
+		return nil, withStatus(http.StatusBadRequest, err)
+	}
+
+	var hooks createHooks
+	if idempotencyKey != "" {
+		hooks.onCommit = func(transaction *sql.Tx, tabloidID int64) error {
+			return mysqlService.InsertProcessedRequest(idempotencyKey, tabloidID, transaction)
+		}
+	}
+	tabloid, _, err := createTabloid(ctx, mysqlService, auditContext, *event, files, limits, hooks)
+	return tabloid, err
+}
+
+// manifestFileErrorStatus returns the status of a manifest file that could not be read: 413 or 400 when it is
+// rejected by the upload limits or the declared type, 500 when it could not be fetched.
+func manifestFileErrorStatus(err error) int {
+	var validationError *utils.ImageValidationError
+	var tooLargeError *utils.UploadTooLargeError
+	if errors.As(err, &validationError) || errors.As(err, &tooLargeError) {
+		return uploadErrorStatus(err)
+	}
+	return http.StatusInternalServerError
+}
+
+// readManifestFile reads one file of a manifest, opened with openFile, within the upload limits.
+func readManifestFile(ctx context.Context, openFile func(ctx context.Context, name string) (io.ReadCloser, error),
+	name string, limits utils.UploadLimits) (interfaces.File, error) {
+	body, err := openFile(ctx, name)
+	if err != nil {
+		return interfaces.File{}, err
+	}
+	defer body.Close()
+	return utils.FileFromReader(name, body, limits)
+}
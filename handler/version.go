@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	uploaderservice "test/lambda/services/uploader-service"
+	urlbuilderservice "test/lambda/services/url-builder-service"
+	"test/lambda/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListVersionsRequest handles GET requests for the versions of one tabloid, oldest first.
+func HandleListVersionsRequest(c *gin.Context) {
+	tabloidID, err := parseTabloidID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	versions, err := mysqlService.GetTabloidVersions(tabloidID)
+	if err != nil {
+		fmt.Println("err de GetTabloidVersions", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// HandleDiffVersionsRequest handles GET requests comparing two versions of a tabloid,
+// given by the "from" and "to" query parameters.
+func HandleDiffVersionsRequest(c *gin.Context) {
+	tabloidID, err := parseTabloidID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: "from and to must be version numbers"})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	fromVersion, err := mysqlService.GetTabloidVersion(tabloidID, from)
+	if err == nil {
+		var toVersion *interfaces.TabloidVersion
+		toVersion, err = mysqlService.GetTabloidVersion(tabloidID, to)
+		if err == nil {
+			c.JSON(http.StatusOK, utils.DiffTabloidVersions(*fromVersion, *toVersion))
+			return
+		}
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Version not found"})
+		return
+	}
+	fmt.Println("err de GetTabloidVersion", err)
+	c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+}
+
+// HandleRestoreVersionRequest handles POST requests restoring an earlier version of a tabloid as the current one.
+// The metadata and the pages of the version are written back to tabloide and imagem_tabloide, pointing to the
+// images already stored in S3, and a new version recording the restore is created.
+// It responds with 409 if any image of the version is no longer in the bucket.
+func HandleRestoreVersionRequest(c *gin.Context) {
+	tabloidID, err := parseTabloidID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: "invalid version: " + c.Param("version")})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	current, err := mysqlService.GetTabloidById(tabloidID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Tabloid not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de GetTabloidById", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	currentPages, err := mysqlService.GetTabloidPages(tabloidID)
+	if err != nil {
+		fmt.Println("err de GetTabloidPages", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	version, err := mysqlService.GetTabloidVersion(tabloidID, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Version not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de GetTabloidVersion", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	// Restoring reuses the stored images, so every one of them must still be in the bucket
+	uploadService, err := uploaderservice.NewUploaderAdapter()
+	if err != nil {
+		fmt.Println("err de uploadService", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	for _, page := range version.Pages {
+		exists, err := uploadService.ObjectExists(imageKeyFromURL(page))
+		if err != nil {
+			fmt.Println("err de ObjectExists", err)
+			c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusConflict, Response{Error: "image no longer stored: " + page})
+			return
+		}
+	}
+
+	urlBuilder, err := urlbuilderservice.NewURLBuilder()
+	if err != nil {
+		fmt.Println("err de NewURLBuilder", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	restored := *current
+	restored.Nome = version.Nome
+	restored.RegiaoID = version.RegiaoID
+	restored.DtInicioVigencia = version.DtInicioVigencia
+	restored.DtFimVigencia = version.DtFimVigencia
+	restored.Ativo = version.Ativo
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		fmt.Println("err de GetTransaction", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	restoredPages, err := restoreVersion(c, mysqlService, transaction, *current, currentPages, restored, version)
+	if err != nil {
+		fmt.Println("err de restoreVersion", err)
+		transaction.Rollback()
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	if err := transaction.Commit(); err != nil {
+		fmt.Println("err de Commit", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	// The feeds of both regions may be cached with their old content; image keys are immutable, so images are not
+	stale := utils.CDNPathsFromEnv()
+	stale.AddRegionFeeds(current.RegiaoID)
+	stale.AddRegionFeeds(restored.RegiaoID)
+	invalidateCDN(c.Request.Context(), stale)
+
+	if err := resolvePageURLs(urlBuilder, restoredPages, restored); err != nil {
+		fmt.Println("err de resolvePageURLs", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tabloide": restored, "paginas": restoredPages})
+}
+
+// restoreVersion writes the restored tabloid and the pages of the version inside the transaction,
+// records the audit trail, creates the version that marks the restore and writes the PagesChanged event.
+func restoreVersion(c *gin.Context, mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	current interfaces.Tabloid, currentPages []interfaces.Page, restored interfaces.Tabloid, version *interfaces.TabloidVersion) ([]interfaces.Page, error) {
+	if err := mysqlService.UpdateTabloid(restored, transaction); err != nil {
+		return nil, err
+	}
+	if err := mysqlService.DeleteTabloidImages(restored.ID, transaction); err != nil {
+		return nil, err
+	}
+
+	// Versions only keep the image keys; checksums are carried over from the current pages when possible
+	checksums := map[string]string{}
+	for _, page := range currentPages {
+		checksums[page.ImageKey] = page.ChecksumSHA256
+	}
+
+	restoredPages := make([]interfaces.Page, 0, len(version.Pages))
+	for order, versionPage := range version.Pages {
+		key := imageKeyFromURL(versionPage)
+		page := interfaces.Page{
+			TabloidID:      restored.ID,
+			ImageKey:       key,
+			StorageID:      storageID(),
+			Order:          order,
+			ChecksumSHA256: checksums[key],
+		}
+		if err := mysqlService.InsertTabloidImage(page, transaction); err != nil {
+			return nil, err
+		}
+		restoredPages = append(restoredPages, page)
+	}
+
+	if err := recordAudit(c, mysqlService, transaction, restored.ID, interfaces.AuditEntityTabloid, interfaces.AuditActionUpdate, current, restored); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(c, mysqlService, transaction, restored.ID, interfaces.AuditEntityPage, interfaces.AuditActionPageChange, currentPages, restoredPages); err != nil {
+		return nil, err
+	}
+
+	restoredFrom := version.Version
+	number, err := snapshotVersion(c, mysqlService, transaction, restored, version.Pages, &restoredFrom)
+	if err != nil {
+		return nil, err
+	}
+	restoredKeys := make([]string, 0, len(restoredPages))
+	for _, page := range restoredPages {
+		restoredKeys = append(restoredKeys, page.ImageKey)
+	}
+	if err := recordEvent(mysqlService, transaction, interfaces.EventPagesChanged, restored, restoredKeys, fmt.Sprintf("v%d", number)); err != nil {
+		return nil, err
+	}
+
+	return restoredPages, nil
+}
+
+// snapshotVersion stores the given state of a tabloid as its next version.
+// restoredFrom is the version being restored, or nil for regular changes.
+func snapshotVersion(c *gin.Context, mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	tabloid interfaces.Tabloid, pages []string, restoredFrom *int) (int, error) {
+	return snapshotVersionAs(utils.GetAuditContext(c), mysqlService, transaction, tabloid, pages, restoredFrom)
+}
+
+// snapshotVersionAs stores the given state of a tabloid as its next version, created outside of a request, such as by a job.
+func snapshotVersionAs(auditContext interfaces.AuditContext, mysqlService *mysqlservice.MysqlTabloideRepository, transaction *sql.Tx,
+	tabloid interfaces.Tabloid, pages []string, restoredFrom *int) (int, error) {
+	version := interfaces.TabloidVersion{
+		TabloidID:        tabloid.ID,
+		Nome:             tabloid.Nome,
+		RegiaoID:         tabloid.RegiaoID,
+		DtInicioVigencia: tabloid.DtInicioVigencia,
+		DtFimVigencia:    tabloid.DtFimVigencia,
+		Ativo:            tabloid.Ativo,
+		Pages:            pages,
+		RestoredFrom:     restoredFrom,
+		Actor:            auditContext.Username,
+	}
+	return mysqlService.InsertTabloidVersion(version, transaction)
+}
+
+// imageKeyFromURL returns the S3 key of an image from its CDN URL, as kept by versions created before the backfill.
+// Keys are returned unchanged.
+func imageKeyFromURL(imageURL string) string {
+	return strings.TrimPrefix(imageURL, os.Getenv("CDN_URL"))
+}
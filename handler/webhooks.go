@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test/lambda/interfaces"
+	mysqlservice "test/lambda/services/mysql-service"
+	webhookservice "test/lambda/services/webhook-service"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookResult summarizes a run of the webhook deliveries.
+type WebhookResult struct {
+	Delivered int `json:"entregues"`   // Deliveries answered with 2xx.
+	Retried   int `json:"reagendadas"` // Failed deliveries scheduled for another attempt.
+	Dead      int `json:"mortas"`      // Failed deliveries given up after WEBHOOK_MAX_ATTEMPTS attempts.
+}
+
+// WebhookPolicy configures the retries of the webhook deliveries.
+type WebhookPolicy struct {
+	BatchSize   int           // Deliveries attempted per run.
+	Concurrency int           // Deliveries sent at a time.
+	MaxAttempts int           // Failed attempts after which a delivery is dead.
+	BaseBackoff time.Duration // Wait after the first failure, doubled after each one.
+	MaxBackoff  time.Duration // Longest wait between attempts.
+	Lease       time.Duration // How long claimed deliveries are left to their dispatcher before being claimed again.
+}
+
+// WebhookPolicyFromEnv reads the WebhookPolicy from WEBHOOK_BATCH_SIZE (100 by default), WEBHOOK_CONCURRENCY (8),
+// WEBHOOK_MAX_ATTEMPTS (8), WEBHOOK_BACKOFF_SECONDS (30), WEBHOOK_MAX_BACKOFF_SECONDS (21600, six hours) and
+// WEBHOOK_LEASE_SECONDS (600), which must exceed the timeout of the function.
+func WebhookPolicyFromEnv() WebhookPolicy {
+	return WebhookPolicy{
+		BatchSize:   utils.GetEnvInt("WEBHOOK_BATCH_SIZE", 100),
+		Concurrency: utils.GetEnvInt("WEBHOOK_CONCURRENCY", 8),
+		MaxAttempts: utils.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		BaseBackoff: time.Duration(utils.GetEnvInt("WEBHOOK_BACKOFF_SECONDS", 30)) * time.Second,
+		MaxBackoff:  time.Duration(utils.GetEnvInt("WEBHOOK_MAX_BACKOFF_SECONDS", 6*60*60)) * time.Second,
+		Lease:       time.Duration(utils.GetEnvInt("WEBHOOK_LEASE_SECONDS", 600)) * time.Second,
+	}
+}
+
+// HandleCreateWebhookRequest handles POST requests subscribing a URL to the domain events, optionally filtered by
+// region and event type. The answer carries the secret the requests are signed with; it is not shown again.
+func HandleCreateWebhookRequest(c *gin.Context) {
+	var request interfaces.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	if err := utils.ValidateStruct(request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error(), Request: request})
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		fmt.Println("err de newWebhookSecret", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+	subscription := interfaces.WebhookSubscription{
+		URL:        request.URL,
+		Secret:     secret,
+		RegionID:   request.RegionID,
+		EventTypes: request.EventTypes,
+		Active:     true,
+		Actor:      utils.GetAuditContext(c).Username,
+		CreatedAt:  time.Now(),
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	subscription.ID, err = mysqlService.InsertWebhookSubscription(subscription)
+	if err != nil {
+		fmt.Println("err de InsertWebhookSubscription", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// HandleListWebhooksRequest handles GET requests for the active webhook subscriptions.
+func HandleListWebhooksRequest(c *gin.Context) {
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	subscriptions, err := mysqlService.GetWebhookSubscriptions()
+	if err != nil {
+		fmt.Println("err de GetWebhookSubscriptions", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// HandleDeleteWebhookRequest handles DELETE requests for one webhook subscription, which stops receiving events.
+func HandleDeleteWebhookRequest(c *gin.Context) {
+	subscriptionID, err := parseWebhookID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	err = mysqlService.DeactivateWebhookSubscription(subscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Webhook not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de DeactivateWebhookSubscription", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleListWebhookDeliveriesRequest handles GET requests for the latest deliveries of one webhook subscription,
+// newest first, up to the "limit" query parameter (100 by default).
+func HandleListWebhookDeliveriesRequest(c *gin.Context) {
+	subscriptionID, err := parseWebhookID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, Response{Error: "limit must be between 1 and 1000"})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	deliveries, err := mysqlService.GetWebhookDeliveries(subscriptionID, limit)
+	if err != nil {
+		fmt.Println("err de GetWebhookDeliveries", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// HandleReplayWebhookDeliveryRequest handles POST requests delivering one event to a webhook again, such as a dead
+// delivery once the partner fixed its endpoint. The delivery is attempted on the next run of the dispatcher.
+func HandleReplayWebhookDeliveryRequest(c *gin.Context) {
+	deliveryID, err := parseWebhookID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	mysqlService := mysqlservice.NewMysqlTabloideRepository()
+	err = mysqlService.ReplayWebhookDelivery(deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, Response{Error: "Delivery not found"})
+		return
+	}
+	if err != nil {
+		fmt.Println("err de ReplayWebhookDelivery", err)
+		c.JSON(http.StatusInternalServerError, Response{Error: err.Error()})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// DeliverWebhooks attempts the webhook deliveries that are due with sender, policy.Concurrency at a time.
+// The deliveries are claimed for policy.Lease in a first transaction, sent outside of any transaction, then their
+// results are recorded in a second one, so no row stays locked while partners answer. Concurrent dispatchers skip
+// each other's deliveries.
+// A failed delivery is attempted again after an exponential backoff and is dead once it failed policy.MaxAttempts
+// times. Receivers drop duplicates by the X-Webhook-Id header: a delivery whose result could not be recorded is sent
+// again once its lease expires.
+func DeliverWebhooks(ctx context.Context, mysqlService *mysqlservice.MysqlTabloideRepository, sender webhookservice.WebhookSender,
+	policy WebhookPolicy) (*WebhookResult, error) {
+	due, err := claimWebhookDeliveries(mysqlService, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	statusCodes := make([]int, len(due))
+	sendErrors := make([]error, len(due))
+	utils.RunBounded(ctx, len(due), policy.Concurrency, func(ctx context.Context, i int) error {
+		statusCodes[i], sendErrors[i] = sender.Send(ctx, due[i].URL, due[i].Secret, due[i].Event)
+		return nil
+	})
+
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	result := &WebhookResult{}
+	for i, webhook := range due {
+		delivery := webhook.Delivery
+		if sendErrors[i] == nil {
+			if err := mysqlService.MarkWebhookDelivered(delivery.ID, statusCodes[i], transaction); err != nil {
+				return nil, err
+			}
+			result.Delivered++
+			continue
+		}
+
+		attempts := delivery.Attempts + 1
+		dead := attempts >= policy.MaxAttempts
+		retryIn := utils.WebhookBackoff(attempts, policy.BaseBackoff, policy.MaxBackoff)
+		if err := mysqlService.MarkWebhookFailed(delivery.ID, statusCodes[i], sendErrors[i].Error(), dead, retryIn, transaction); err != nil {
+			return nil, err
+		}
+		if dead {
+			result.Dead++
+		} else {
+			result.Retried++
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// claimWebhookDeliveries claims the due deliveries for policy.Lease and commits the claim.
+func claimWebhookDeliveries(mysqlService *mysqlservice.MysqlTabloideRepository, policy WebhookPolicy) ([]interfaces.DueWebhook, error) {
+	transaction, err := mysqlService.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	due, err := mysqlService.ClaimDueWebhookDeliveries(policy.BatchSize, policy.Lease, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// newWebhookSecret generates the secret of a new subscription: 32 random bytes, hex-encoded.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %v", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// parseWebhookID reads the :id path parameter as the ID of a webhook subscription or delivery.
+func parseWebhookID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id: %s", c.Param("id"))
+	}
+	return id, nil
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+	"test/lambda/interfaces"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// fakeSender answers each webhook URL with the status code set for it, failing unless it is a 2xx.
+type fakeSender struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	sent     []string
+}
+
+func (s *fakeSender) Send(ctx context.Context, url, secret string, event interfaces.DomainEvent) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, url)
+	status := s.statuses[url]
+	if status < 200 || status > 299 {
+		return status, errors.New("webhook answered " + url)
+	}
+	return status, nil
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	mysqlService, mock := newMockRepository(t)
+	policy := WebhookPolicy{BatchSize: 100, Concurrency: 2, MaxAttempts: 8, BaseBackoff: 30 * time.Second,
+		MaxBackoff: 6 * time.Hour, Lease: 10 * time.Minute}
+	sender := &fakeSender{statuses: map[string]int{
+		"https://parceiro.example.com/expirado": 200,
+		"https://parceiro.example.com/falha":    503,
+		"https://parceiro.example.com/morto":    500,
+	}}
+
+	columns := []string{"id", "assinatura_id", "evento_id", "tipo", "id_deduplicacao", "status", "tentativas",
+		"ultimo_status_http", "ultimo_erro", "dt_proxima_tentativa", "dt_entrega", "dt_cadastro",
+		"url", "segredo", "tabloide_id", "regiao_id", "payload", "evento_dt_cadastro"}
+	due := func(id int64, status string, attempts int, url string) []driver.Value {
+		return []driver.Value{id, 3, 7, interfaces.EventTabloidPublished, "TabloidPublished:42:20261019", status, attempts,
+			nil, nil, "2026-10-19 03:00:00", nil, "2026-10-19 02:00:00", url, "segredo", 42, 144, `{"id":42}`, "2026-10-19 02:00:00"}
+	}
+
+	mock.ExpectBegin()
+	// Deliveries left sending by a dispatcher whose lease expired are due again
+	mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_entrega e")).
+		WithArgs(interfaces.WebhookDeliveryPending, interfaces.WebhookDeliverySending, 100).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(due(1, interfaces.WebhookDeliverySending, 0, "https://parceiro.example.com/expirado")...).
+			AddRow(due(2, interfaces.WebhookDeliveryPending, 2, "https://parceiro.example.com/falha")...).
+			AddRow(due(3, interfaces.WebhookDeliveryPending, 7, "https://parceiro.example.com/morto")...))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_entrega SET status = ?, dt_proxima_tentativa")).
+		WithArgs(interfaces.WebhookDeliverySending, 600, 1, 2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	failedQuery := regexp.QuoteMeta("UPDATE webhook_entrega SET status = ?, tentativas = tentativas + 1")
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("dt_entrega = NOW() WHERE id = ?")).
+		WithArgs(interfaces.WebhookDeliveryDelivered, 200, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Third failure: attempted again after 30s doubled twice
+	mock.ExpectExec(failedQuery).
+		WithArgs(interfaces.WebhookDeliveryPending, 503, "webhook answered https://parceiro.example.com/falha", 120, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Eighth failure: dead
+	mock.ExpectExec(failedQuery).
+		WithArgs(interfaces.WebhookDeliveryDead, 500, "webhook answered https://parceiro.example.com/morto", 3840, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := DeliverWebhooks(context.Background(), mysqlService, sender, policy)
+	if err != nil {
+		t.Fatalf("DeliverWebhooks returned error: %v", err)
+	}
+	if *result != (WebhookResult{Delivered: 1, Retried: 1, Dead: 1}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	sort.Strings(sender.sent)
+	expected := []string{"https://parceiro.example.com/expirado", "https://parceiro.example.com/falha", "https://parceiro.example.com/morto"}
+	for i := range expected {
+		if i >= len(sender.sent) || sender.sent[i] != expected[i] {
+			t.Fatalf("DeliverWebhooks sent to %v, expected %v", sender.sent, expected)
+		}
+	}
+}
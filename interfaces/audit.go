@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entities.
+const (
+	AuditEntityTabloid = "tabloide"
+	AuditEntityPage    = "imagem_tabloide"
+)
+
+// Audited actions.
+const (
+	AuditActionCreate     = "create"
+	AuditActionUpdate     = "update"
+	AuditActionDelete     = "delete"
+	AuditActionPageChange = "page_change"
+	AuditActionArchive    = "archive"
+	AuditActionUnarchive  = "unarchive"
+)
+
+// AuditContext identifies who performed a mutation and in which request.
+type AuditContext struct {
+	Username  string // Username forwarded by the authorizer in the x-username header.
+	RequestID string // ID of the API Gateway request (or a generated one when running locally).
+}
+
+// AuditEntry represents one row of the auditoria_tabloide table.
+type AuditEntry struct {
+	ID        int64           `json:"id"`                       // ID of the audit entry.
+	TabloidID int64           `json:"tabloide_id"`              // ID of the tabloid affected by the mutation.
+	Entity    string          `json:"entidade"`                 // Entity that was changed (tabloide, imagem_tabloide).
+	Action    string          `json:"acao"`                     // Kind of mutation (create, update, delete, page_change, archive, unarchive).
+	Actor     string          `json:"usuario"`                  // Username of whoever performed the mutation.
+	RequestID string          `json:"request_id"`               // ID of the request that performed the mutation.
+	Before    json.RawMessage `json:"valor_anterior,omitempty"` // JSON value of the entity before the mutation.
+	After     json.RawMessage `json:"valor_novo,omitempty"`     // JSON value of the entity after the mutation.
+	CreatedAt time.Time       `json:"dt_cadastro"`              // When the mutation was recorded.
+}
@@ -0,0 +1,33 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of domain events.
+const (
+	EventTabloidCreated   = "TabloidCreated"   // A tabloid was created with its pages.
+	EventPagesChanged     = "PagesChanged"     // The pages of a tabloid changed.
+	EventTabloidPublished = "TabloidPublished" // The validity of a tabloid started.
+	EventTabloidExpired   = "TabloidExpired"   // The validity of a tabloid ended.
+)
+
+// DomainEvent represents one row of the outbox_evento table: an event written in the transaction of the change it
+// describes and delivered later, at least once, by the dispatcher.
+type DomainEvent struct {
+	ID              int64           `json:"-"`                // ID of the outbox row.
+	DeduplicationID string          `json:"deduplication_id"` // Stable ID of the event, for consumers to drop duplicates.
+	Type            string          `json:"type"`             // Type of the event (TabloidCreated, PagesChanged, ...).
+	TabloidID       int64           `json:"tabloide_id"`      // ID of the tabloid the event is about.
+	RegionID        int             `json:"regiao_id"`        // Region of the tabloid.
+	Payload         json.RawMessage `json:"payload"`          // JSON body of the event (see EventPayload).
+	Attempts        int             `json:"-"`                // Failed delivery attempts so far.
+	OccurredAt      time.Time       `json:"occurred_at"`      // When the event was written.
+}
+
+// EventPayload is the body of the domain events: the tabloid and, for events about pages, the keys of its pages.
+type EventPayload struct {
+	Tabloid Tabloid  `json:"tabloide"`
+	Pages   []string `json:"paginas,omitempty"`
+}
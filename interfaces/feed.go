@@ -0,0 +1,13 @@
+package interfaces
+
+// FeedTabloid represents a tabloid published in a region feed, along with its pages.
+type FeedTabloid struct {
+	Tabloid
+	Pages []Page `json:"paginas"` // Pages of the tabloid, in page order.
+}
+
+// RegionFeed represents the currently valid, active tabloids of a region.
+type RegionFeed struct {
+	RegionID int           `json:"regiao_id"` // ID of the region.
+	Tabloids []FeedTabloid `json:"tabloides"` // Currently valid tabloids of the region.
+}
@@ -0,0 +1,46 @@
+package interfaces
+
+import "time"
+
+// Statuses of the readiness checks.
+const (
+	HealthStatusOK   = "ok"    // Every check passed.
+	HealthStatusFail = "falha" // At least one check failed or timed out.
+)
+
+// HealthCheck is the result of one readiness check.
+type HealthCheck struct {
+	Name      string `json:"nome"`           // What was checked, such as "mysql".
+	Status    string `json:"status"`         // Status of the check (ok, falha).
+	LatencyMs int64  `json:"latencia_ms"`    // How long the check took, up to the timeout.
+	Error     string `json:"erro,omitempty"` // Why the check failed; only shown by the diagnostics endpoint.
+}
+
+// ReadinessReport is the body of the readiness endpoint.
+type ReadinessReport struct {
+	Status string        `json:"status"`    // ok when every check passed, falha otherwise.
+	Checks []HealthCheck `json:"checagens"` // Result of each check, in the order they are declared.
+}
+
+// PoolStats reports the connection pool of the database.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_conexoes"`           // Maximum open connections, 0 for no limit.
+	OpenConnections    int   `json:"conexoes_abertas"`       // Connections open, in use or idle.
+	InUse              int   `json:"em_uso"`                 // Connections in use.
+	Idle               int   `json:"ociosas"`                // Connections idle.
+	WaitCount          int64 `json:"esperas"`                // Times a connection was waited for.
+	WaitDurationMs     int64 `json:"espera_ms"`              // Total time waited for connections.
+	MaxIdleClosed      int64 `json:"fechadas_max_ociosas"`   // Connections closed by the idle limit.
+	MaxIdleTimeClosed  int64 `json:"fechadas_tempo_ocioso"`  // Connections closed by the idle time limit.
+	MaxLifetimeClosed  int64 `json:"fechadas_tempo_de_vida"` // Connections closed by the lifetime limit.
+}
+
+// Diagnostics is the body of the diagnostics endpoint.
+type Diagnostics struct {
+	Version   string            `json:"versao"`               // Version the binary was built from.
+	GoVersion string            `json:"versao_go"`            // Go version the binary was built with.
+	StartedAt time.Time         `json:"dt_inicio"`            // When the process started.
+	Config    map[string]string `json:"configuracao"`         // Environment variables, with secrets masked.
+	Pool      *PoolStats        `json:"pool_mysql,omitempty"` // Connection pool of the database, if it could be opened.
+	Readiness ReadinessReport   `json:"prontidao"`            // Readiness checks, with the errors of the failed ones.
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import "time"
+
+// InboxErrorReport is written next to the files of a tabloid dropped in the S3 inbox that could not be created.
+type InboxErrorReport struct {
+	Manifest string    `json:"manifest"`         // Key the manifest was dropped under.
+	Error    string    `json:"erro"`             // Why the tabloid could not be created.
+	Code     string    `json:"codigo,omitempty"` // Code of the failure, as in the response of POST /test.
+	Files    []string  `json:"arquivos"`         // Keys the files were moved to.
+	FailedAt time.Time `json:"dt_falha"`         // When the tabloid failed.
+}
@@ -0,0 +1,51 @@
+package interfaces
+
+import "time"
+
+// Statuses of a job.
+const (
+	JobStatusQueued    = "queued"    // Accepted and waiting in the queue.
+	JobStatusRunning   = "running"   // Taken by a consumer.
+	JobStatusSucceeded = "succeeded" // The tabloid was created.
+	JobStatusFailed    = "failed"    // The tabloid could not be created; see Error.
+)
+
+// Statuses of one page of a job.
+const (
+	JobPageStatusUploaded = "uploaded"
+	JobPageStatusFailed   = "failed"
+)
+
+// Job represents one row of the job_tabloide table: a tabloid creation processed asynchronously.
+type Job struct {
+	ID             string       `json:"id"`                    // ID of the job (UUID).
+	Status         string       `json:"status"`                // Status of the job (queued, running, succeeded, failed).
+	TabloidID      *int64       `json:"tabloide_id,omitempty"` // ID of the created tabloid, once succeeded.
+	TotalPages     int          `json:"total_paginas"`         // Number of pages of the upload, once read.
+	ProcessedPages int          `json:"paginas_processadas"`   // Number of pages uploaded or failed so far.
+	Pages          []JobPage    `json:"paginas"`               // Result of each processed page, in page order.
+	Error          string       `json:"erro,omitempty"`        // Why the job failed.
+	Code           string       `json:"codigo,omitempty"`      // Code of the failure, as in the synchronous response.
+	Request        RequestEvent `json:"requisicao"`            // Request that created the job, without the file content.
+	FileKey        string       `json:"-"`                     // Key under which the uploaded file is staged in S3.
+	Actor          string       `json:"usuario"`               // Username of whoever created the job.
+	RequestID      string       `json:"request_id"`            // ID of the request that created the job.
+	CreatedAt      time.Time    `json:"dt_cadastro"`           // When the job was created.
+	UpdatedAt      time.Time    `json:"dt_alteracao"`          // When the job last changed.
+}
+
+// JobPage is the result of one page of a job.
+type JobPage struct {
+	Order      int    `json:"ordem"`                // Position of the page in the tabloid.
+	Status     string `json:"status"`               // Status of the page (uploaded, failed).
+	ImageKey   string `json:"imagem_key,omitempty"` // Key under which the page was stored.
+	Variants   int    `json:"variantes"`            // Number of resized copies stored.
+	DurationMs int64  `json:"duracao_ms"`           // How long the upload took.
+	Error      string `json:"erro,omitempty"`       // Why the upload failed.
+	Code       string `json:"codigo,omitempty"`     // Code of the failure, if any.
+}
+
+// JobMessage is the body of the queue messages that hand a job to the consumer.
+type JobMessage struct {
+	JobID string `json:"job_id"` // ID of the job to process.
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+// StorageIDArchive is the storage backend of the pages of archived tabloids, kept in the archive location.
+const StorageIDArchive = "archive"
+
+// Page represents one page (imagem_tabloide row) of a tabloid.
+// The database stores the object key and the storage backend of the image; ImageURL is resolved at read time.
+type Page struct {
+	ID             int64         `json:"id"`                        // ID of the imagem_tabloide row.
+	TabloidID      int64         `json:"tabloide_id"`               // ID of the tabloid the page belongs to.
+	ImageKey       string        `json:"imagem_key,omitempty"`      // Key of the page image in its storage backend.
+	StorageID      string        `json:"storage_id,omitempty"`      // ID of the storage backend holding the page image.
+	ImageURL       string        `json:"imagem_url,omitempty"`      // URL of the page image, built from the key when read.
+	Order          int           `json:"ordem"`                     // Zero-based position of the page in the tabloid.
+	ChecksumSHA256 string        `json:"checksum_sha256,omitempty"` // Hex SHA-256 of the page image, if known.
+	Variants       []PageVariant `json:"variantes,omitempty"`       // Resized copies of the page image, narrowest first.
+}
+
+// PageVariant represents a resized copy of a page image, suitable for building a srcset.
+type PageVariant struct {
+	Width    int    `json:"largura"`              // Width of the copy in pixels.
+	Key      string `json:"-"`                    // Key of the copy in the storage backend of the page image.
+	ImageURL string `json:"imagem_url,omitempty"` // URL of the copy, built from the key when read.
+}
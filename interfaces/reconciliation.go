@@ -0,0 +1,31 @@
+package interfaces
+
+import "time"
+
+// StoredObject represents an object listed from the S3 bucket.
+type StoredObject struct {
+	Key          string    `json:"key"`           // Key of the object.
+	Size         int64     `json:"size"`          // Size of the object in bytes.
+	LastModified time.Time `json:"last_modified"` // When the object was written.
+}
+
+// Actions a reconciliation can take on orphan objects.
+const (
+	ReconcileActionReport     = "report"     // Only report the orphans.
+	ReconcileActionQuarantine = "quarantine" // Move the orphans under the quarantine prefix.
+	ReconcileActionDelete     = "delete"     // Delete the orphans.
+)
+
+// ReconciliationReport describes the differences between the objects stored in the bucket and the keys
+// referenced by the database.
+type ReconciliationReport struct {
+	CheckedAt     time.Time      `json:"checked_at"`     // When the reconciliation ran.
+	Prefixes      []string       `json:"prefixes"`       // Key prefixes listed.
+	Objects       int            `json:"objects"`        // Number of objects listed.
+	Referenced    int            `json:"referenced"`     // Number of distinct keys referenced by the database.
+	Orphans       []StoredObject `json:"orphans"`        // Objects no row references, older than the grace period.
+	RecentOrphans []StoredObject `json:"recent_orphans"` // Objects no row references yet, still within the grace period.
+	Missing       []string       `json:"missing"`        // Referenced keys under the listed prefixes with no object.
+	Action        string         `json:"action"`         // Action taken on Orphans.
+	Processed     []string       `json:"processed"`      // Orphans the action was applied to.
+}
@@ -3,16 +3,16 @@ package interfaces
 
 import (
 	"fmt"
-	"mime/multipart"
 	"time"
 )
 
 // File represents a file uploaded via HTTP.
 type File struct {
-	Name        string                `json:"name"`                                                                  // Name of the file.
-	ContentType string                `json:"content_type" validate:"required,oneof=image/png image/jpg image/jpeg"` // Content type of the file.
-	Size        int64                 `json:"size"`                                                                  // Size of the file in bytes.
-	Data        *multipart.FileHeader `json:"-"`                                                                     // File data.
+	Name        string `json:"name"`                                                                                                                                    // Name of the file.
+	ContentType string `json:"content_type" validate:"required,oneof=image/png image/jpg image/jpeg image/webp image/gif application/zip application/x-zip-compressed"` // Content type of the file.
+	Size        int64  `json:"size"`                                                                                                                                    // Size of the file in bytes.
+	Content     []byte `json:"-"`                                                                                                                                       // File content, for images.
+	Path        string `json:"-"`                                                                                                                                       // Temporary file holding the content of ZIP archives, which are not kept in memory.
 }
 
 // RequestEvent represents an event request.
@@ -26,5 +26,15 @@ type RequestEvent struct {
 
 // This method uses fmt.Sprintf() to format a string containing all of File's attributes
 func (f File) String() string {
-	return fmt.Sprintf("Name: %s, ContentType: %s, Size: %d, Data: %s", f.Name, f.ContentType, f.Size, f.Data.Filename)
+	return fmt.Sprintf("Name: %s, ContentType: %s, Size: %d", f.Name, f.ContentType, f.Size)
+}
+
+// TabloidManifest describes a tabloid whose pages are already stored in S3, as sent to the tabloid queue by
+// upstream systems or dropped in the S3 inbox next to its page files.
+type TabloidManifest struct {
+	Name              string   `json:"name"`                // Name of the tabloid.
+	RegionID          int      `json:"region_id"`           // ID of the region of the tabloid.
+	StartValidityDate string   `json:"start_validity_date"` // Start of validity, as YYYY-MM-DD.
+	EndValidityDate   string   `json:"end_validity_date"`   // End of validity, as YYYY-MM-DD.
+	Files             []string `json:"files"`               // Page files, in page order: images, ZIP archives or both.
 }
@@ -3,11 +3,12 @@ package interfaces
 import "time"
 
 type Tabloid struct {
-	Nome             string
-	DtInicioVigencia time.Time
-	DtFimVigencia    time.Time
-	Ativo            bool
-	DtCadastro       time.Time
-	DtAlteracao      time.Time
-	RegiaoID         int
+	ID               int64     `json:"id"`
+	Nome             string    `json:"nome"`
+	DtInicioVigencia time.Time `json:"dt_inicio_vigencia"`
+	DtFimVigencia    time.Time `json:"dt_fim_vigencia"`
+	Ativo            bool      `json:"ativo"`
+	DtCadastro       time.Time `json:"dt_cadastro"`
+	DtAlteracao      time.Time `json:"dt_alteracao"`
+	RegiaoID         int       `json:"regiao_id"`
 }
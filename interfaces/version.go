@@ -0,0 +1,44 @@
+package interfaces
+
+import "time"
+
+// TabloidVersion represents an immutable snapshot (versao_tabloide row) of a tabloid:
+// its metadata plus the ordered list of its page images.
+type TabloidVersion struct {
+	ID               int64     `json:"id"`                      // ID of the versao_tabloide row.
+	TabloidID        int64     `json:"tabloide_id"`             // ID of the versioned tabloid.
+	Version          int       `json:"versao"`                  // Sequential version number, starting at 1 for each tabloid.
+	Nome             string    `json:"nome"`                    // Name of the tabloid at this version.
+	RegiaoID         int       `json:"regiao_id"`               // Region of the tabloid at this version.
+	DtInicioVigencia time.Time `json:"dt_inicio_vigencia"`      // Start of validity at this version.
+	DtFimVigencia    time.Time `json:"dt_fim_vigencia"`         // End of validity at this version.
+	Ativo            bool      `json:"ativo"`                   // Whether the tabloid was active at this version.
+	Pages            []string  `json:"paginas"`                 // Keys of the page images, in page order.
+	RestoredFrom     *int      `json:"restaurado_de,omitempty"` // Version restored to create this one, if any.
+	Actor            string    `json:"usuario"`                 // Username of whoever created this version.
+	CreatedAt        time.Time `json:"dt_cadastro"`             // When this version was created.
+}
+
+// VersionFieldChange describes a metadata field that differs between two versions.
+type VersionFieldChange struct {
+	Field string      `json:"campo"` // Name of the changed field.
+	From  interface{} `json:"de"`    // Value in the older version.
+	To    interface{} `json:"para"`  // Value in the newer version.
+}
+
+// VersionPageChange describes a page position whose image differs between two versions.
+// From is empty when the page was added and To is empty when the page was removed.
+type VersionPageChange struct {
+	Order int    `json:"ordem"` // Zero-based position of the page.
+	From  string `json:"de"`    // Page image in the older version.
+	To    string `json:"para"`  // Page image in the newer version.
+}
+
+// VersionDiff represents the differences between two versions of a tabloid.
+type VersionDiff struct {
+	TabloidID int64                `json:"tabloide_id"` // ID of the compared tabloid.
+	From      int                  `json:"de"`          // Older version number.
+	To        int                  `json:"para"`        // Newer version number.
+	Fields    []VersionFieldChange `json:"campos"`      // Changed metadata fields.
+	Pages     []VersionPageChange  `json:"paginas"`     // Changed pages.
+}
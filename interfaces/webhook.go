@@ -0,0 +1,54 @@
+package interfaces
+
+import "time"
+
+// Statuses of a webhook delivery.
+const (
+	WebhookDeliveryPending   = "pending"   // Waiting for its next attempt.
+	WebhookDeliverySending   = "sending"   // Claimed by a dispatcher until dt_proxima_tentativa, its lease.
+	WebhookDeliveryDelivered = "delivered" // Answered with 2xx.
+	WebhookDeliveryDead      = "dead"      // Gave up after WEBHOOK_MAX_ATTEMPTS attempts; can be replayed.
+)
+
+// WebhookSubscription represents one row of the webhook_assinatura table: a partner URL notified of domain events.
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`                  // ID of the subscription.
+	URL        string    `json:"url"`                 // URL the events are POSTed to.
+	Secret     string    `json:"segredo,omitempty"`   // HMAC-SHA256 key of the signatures; only returned on creation.
+	RegionID   *int      `json:"regiao_id,omitempty"` // Region the events are filtered by, nil for every region.
+	EventTypes []string  `json:"tipos"`               // Types the events are filtered by, empty for every type.
+	Active     bool      `json:"ativo"`               // Whether events are still delivered.
+	Actor      string    `json:"usuario"`             // Username of whoever created the subscription.
+	CreatedAt  time.Time `json:"dt_cadastro"`         // When the subscription was created.
+}
+
+// WebhookSubscriptionRequest is the body of the requests creating a webhook subscription.
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url,startswith=https://"`                                         // HTTPS URL the events are POSTed to.
+	RegionID   *int     `json:"regiao_id" validate:"omitempty,min=1"`                                                    // Region to filter by, if any.
+	EventTypes []string `json:"tipos" validate:"dive,oneof=TabloidCreated PagesChanged TabloidPublished TabloidExpired"` // Types to filter by, if any.
+}
+
+// WebhookDelivery represents one row of the webhook_entrega table: the delivery of one event to one subscription.
+type WebhookDelivery struct {
+	ID              int64      `json:"id"`                           // ID of the delivery.
+	SubscriptionID  int64      `json:"assinatura_id"`                // Subscription the event is delivered to.
+	EventID         int64      `json:"evento_id"`                    // Outbox event delivered.
+	EventType       string     `json:"tipo"`                         // Type of the event.
+	DeduplicationID string     `json:"deduplication_id"`             // Deduplication ID of the event.
+	Status          string     `json:"status"`                       // Status of the delivery (pending, sending, delivered, dead).
+	Attempts        int        `json:"tentativas"`                   // Failed attempts so far.
+	LastStatusCode  *int       `json:"ultimo_status_http,omitempty"` // HTTP status of the last attempt, if answered.
+	LastError       string     `json:"ultimo_erro,omitempty"`        // Why the last attempt failed.
+	NextAttemptAt   time.Time  `json:"dt_proxima_tentativa"`         // When the next attempt is due.
+	DeliveredAt     *time.Time `json:"dt_entrega,omitempty"`         // When the event was delivered.
+	CreatedAt       time.Time  `json:"dt_cadastro"`                  // When the delivery was created.
+}
+
+// DueWebhook is a delivery due for an attempt, with what is needed to send it.
+type DueWebhook struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Event    DomainEvent
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	usecase "test/lambda/handler"
+	uploaderservice "test/lambda/services/uploader-service"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -17,10 +18,32 @@ var ginLambda *ginadapter.GinLambdaV2 // TODO documentar sobre a integração V2
 
 func init() {
 	r := gin.Default()
-	r.POST("/dev/test", usecase.HandlePostRequest)
+	registerRoutes(r.Group("/dev"))
 	ginLambda = ginadapter.NewV2(r)
 }
 
+// registerRoutes registers every HTTP route of the application under the given router.
+func registerRoutes(r gin.IRouter) {
+	r.GET("/health", usecase.HandleHealthRequest)
+	r.GET("/ready", usecase.HandleReadyRequest)
+	r.GET("/diagnostics", usecase.HandleDiagnosticsRequest)
+	r.POST("/test", usecase.HandlePostRequest)
+	r.GET("/jobs/:id", usecase.HandleGetJobRequest)
+	r.GET("/tabloids/:id/audit", usecase.HandleGetAuditRequest)
+	r.GET("/tabloids/:id/versions", usecase.HandleListVersionsRequest)
+	r.GET("/tabloids/:id/versions/diff", usecase.HandleDiffVersionsRequest)
+	r.POST("/tabloids/:id/versions/:version/restore", usecase.HandleRestoreVersionRequest)
+	r.POST("/tabloids/:id/archive/restore", usecase.HandleRestoreArchivedTabloidRequest)
+	r.GET("/regions/:id/tabloids", usecase.HandleRegionFeedRequest)
+	r.GET("/regions/:id/feed.atom", usecase.HandleRegionAtomRequest)
+	r.GET("/regions/:id/calendar.ics", usecase.HandleRegionCalendarRequest)
+	r.POST("/webhooks", usecase.HandleCreateWebhookRequest)
+	r.GET("/webhooks", usecase.HandleListWebhooksRequest)
+	r.DELETE("/webhooks/:id", usecase.HandleDeleteWebhookRequest)
+	r.GET("/webhooks/:id/deliveries", usecase.HandleListWebhookDeliveriesRequest)
+	r.POST("/webhooks/deliveries/:id/replay", usecase.HandleReplayWebhookDeliveryRequest)
+}
+
 func HandleRequest(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	return ginLambda.ProxyWithContext(ctx, req)
 }
@@ -33,14 +56,37 @@ func main() {
 			fmt.Println("Erro ao carregar variáveis de ambiente", err)
 			return
 		}
-		if os.Getenv("ENVIRONMENT") == "dev" {
-			r := gin.Default()
-			r.POST("/test", usecase.HandlePostRequest)
-			address := fmt.Sprintf(":%s", os.Getenv("PORT"))
-			r.Run(address)
-		}
 	}
 
-	lambda.Start(HandleRequest)
+	// Fail at startup, not on the first upload, if the S3 key layout is invalid
+	if _, err := uploaderservice.ImageKeyTemplate(); err != nil {
+		fmt.Println("Configuração inválida", err)
+		return
+	}
+
+	if os.Getenv("STAGE") == "dev" && os.Getenv("ENVIRONMENT") == "dev" {
+		r := gin.Default()
+		registerRoutes(r)
+		address := fmt.Sprintf(":%s", os.Getenv("PORT"))
+		r.Run(address)
+	}
+
+	// Every function of serverless.yml runs this binary; LAMBDA_HANDLER selects what it handles
+	switch os.Getenv("LAMBDA_HANDLER") {
+	case "reconcile-storage":
+		lambda.Start(usecase.HandleReconcileStorageEvent)
+	case "archive-tabloids":
+		lambda.Start(usecase.HandleArchiveTabloidsEvent)
+	case "process-jobs":
+		lambda.Start(usecase.HandleJobQueueEvent)
+	case "tabloid-queue":
+		lambda.Start(usecase.HandleTabloidQueueEvent)
+	case "inbox":
+		lambda.Start(usecase.HandleInboxEvent)
+	case "dispatch-events":
+		lambda.Start(usecase.HandleDispatchEventsEvent)
+	default:
+		lambda.Start(HandleRequest)
+	}
 
 }
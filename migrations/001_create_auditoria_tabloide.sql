@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS auditoria_tabloide (
+    id             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    tabloide_id    BIGINT UNSIGNED NOT NULL,
+    entidade       VARCHAR(32)     NOT NULL,
+    acao           VARCHAR(32)     NOT NULL,
+    usuario        VARCHAR(255)    NOT NULL,
+    request_id     VARCHAR(64)     NOT NULL,
+    valor_anterior JSON            NULL,
+    valor_novo     JSON            NULL,
+    dt_cadastro    DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_auditoria_tabloide_tabloide (tabloide_id, dt_cadastro)
+);
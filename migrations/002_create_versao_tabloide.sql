@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS versao_tabloide (
+    id                 BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    tabloide_id        BIGINT UNSIGNED NOT NULL,
+    versao             INT UNSIGNED    NOT NULL,
+    nome               VARCHAR(255)    NOT NULL,
+    regiao_id          INT UNSIGNED    NOT NULL,
+    dt_inicio_vigencia DATETIME        NOT NULL,
+    dt_fim_vigencia    DATETIME        NOT NULL,
+    ativo              TINYINT(1)      NOT NULL,
+    paginas            JSON            NOT NULL,
+    restaurado_de      INT UNSIGNED    NULL,
+    usuario            VARCHAR(255)    NOT NULL,
+    dt_cadastro        DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_versao_tabloide (tabloide_id, versao)
+);
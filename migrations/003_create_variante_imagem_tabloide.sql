@@ -0,0 +1,10 @@
+-- The URLs are limited to 512 characters: with utf8mb4, the unique key must stay within the 3072 bytes of an InnoDB index.
+CREATE TABLE IF NOT EXISTS variante_imagem_tabloide (
+    id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    imagem_url   VARCHAR(512)    NOT NULL,
+    largura      INT UNSIGNED    NOT NULL,
+    variante_url VARCHAR(512)    NOT NULL,
+    dt_cadastro  DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_variante_imagem_tabloide (imagem_url, largura)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE imagem_tabloide
+    ADD COLUMN checksum_sha256 CHAR(64) NULL AFTER imagem_url,
+    ADD KEY idx_imagem_tabloide_checksum (checksum_sha256);
@@ -0,0 +1,15 @@
+-- Pages, variants and versions store object keys; URLs are built at read time.
+-- imagem_url and variante_url are kept for rows not converted by the backfill-image-keys command.
+-- The keys are limited to 512 characters: with utf8mb4, their indexes must stay within the 3072 bytes InnoDB allows.
+ALTER TABLE imagem_tabloide
+    MODIFY COLUMN imagem_url VARCHAR(768) NULL,
+    ADD COLUMN imagem_key VARCHAR(512) NULL AFTER imagem_url,
+    ADD COLUMN storage_id VARCHAR(64) NULL AFTER imagem_key,
+    ADD KEY idx_imagem_tabloide_key (imagem_key);
+
+ALTER TABLE variante_imagem_tabloide
+    MODIFY COLUMN imagem_url VARCHAR(512) NULL,
+    MODIFY COLUMN variante_url VARCHAR(512) NULL,
+    ADD COLUMN imagem_key VARCHAR(512) NULL AFTER imagem_url,
+    ADD COLUMN variante_key VARCHAR(512) NULL AFTER variante_url,
+    ADD UNIQUE KEY uk_variante_imagem_tabloide_key (imagem_key, largura);
@@ -0,0 +1,5 @@
+-- Tabloids expired for ARCHIVE_AFTER_DAYS are archived: their pages move to the archive location
+-- (imagem_tabloide.storage_id = 'archive') and dt_arquivamento records when.
+ALTER TABLE tabloide
+    ADD COLUMN dt_arquivamento DATETIME NULL,
+    ADD KEY idx_tabloide_arquivamento (dt_arquivamento, dt_fim_vigencia);
@@ -0,0 +1,19 @@
+-- Tabloids created with ?async=true are processed by the job consumer; job_tabloide tracks their progress.
+-- The uploaded file waits under JOB_STAGING_PREFIX in the bucket (arquivo_key) until the job ends.
+CREATE TABLE IF NOT EXISTS job_tabloide (
+    id                  CHAR(36)        NOT NULL,
+    status              VARCHAR(16)     NOT NULL,
+    tabloide_id         BIGINT UNSIGNED NULL,
+    total_paginas       INT UNSIGNED    NOT NULL DEFAULT 0,
+    paginas             JSON            NOT NULL,
+    erro                TEXT            NULL,
+    codigo              VARCHAR(64)     NULL,
+    requisicao          JSON            NOT NULL,
+    arquivo_key         VARCHAR(1024)   NOT NULL,
+    usuario             VARCHAR(255)    NOT NULL,
+    request_id          VARCHAR(255)    NOT NULL,
+    dt_cadastro         DATETIME        NOT NULL,
+    dt_alteracao        DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_job_tabloide_status (status, dt_cadastro)
+);
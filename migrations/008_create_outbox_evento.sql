@@ -0,0 +1,18 @@
+-- Domain events are written here in the transaction of the change they describe (transactional outbox),
+-- then delivered to EVENT_SINK by the dispatch-events function. id_deduplicacao makes writes idempotent
+-- and is sent along so consumers can drop the duplicates of at-least-once delivery.
+CREATE TABLE IF NOT EXISTS outbox_evento (
+    id                  BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    id_deduplicacao     VARCHAR(255)    NOT NULL,
+    tipo                VARCHAR(64)     NOT NULL,
+    tabloide_id         BIGINT UNSIGNED NOT NULL,
+    regiao_id           INT UNSIGNED    NOT NULL,
+    payload             JSON            NOT NULL,
+    tentativas          INT UNSIGNED    NOT NULL DEFAULT 0,
+    ultimo_erro         TEXT            NULL,
+    dt_cadastro         DATETIME        NOT NULL,
+    dt_envio            DATETIME        NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_outbox_evento_deduplicacao (id_deduplicacao),
+    KEY idx_outbox_evento_pendente (dt_envio, id)
+);
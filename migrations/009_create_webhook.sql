@@ -0,0 +1,32 @@
+-- Partners subscribe to the domain events of the outbox; the dispatcher creates one webhook_entrega per event and
+-- matching subscription and POSTs it, signed with segredo, retrying with exponential backoff until it is delivered
+-- or WEBHOOK_MAX_ATTEMPTS attempts failed (status dead, which can be replayed). While a dispatcher sends it, a delivery
+-- is sending and dt_proxima_tentativa is the end of its lease, after which another dispatcher claims it again.
+CREATE TABLE IF NOT EXISTS webhook_assinatura (
+    id                  BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    url                 VARCHAR(2048)   NOT NULL,
+    segredo             VARCHAR(128)    NOT NULL,
+    regiao_id           INT UNSIGNED    NULL,
+    tipos               JSON            NOT NULL,
+    ativo               TINYINT(1)      NOT NULL DEFAULT 1,
+    usuario             VARCHAR(255)    NOT NULL,
+    dt_cadastro         DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_webhook_assinatura_ativo (ativo, regiao_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_entrega (
+    id                   BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    assinatura_id        BIGINT UNSIGNED NOT NULL,
+    evento_id            BIGINT UNSIGNED NOT NULL,
+    status               VARCHAR(16)     NOT NULL,
+    tentativas           INT UNSIGNED    NOT NULL DEFAULT 0,
+    ultimo_status_http   INT             NULL,
+    ultimo_erro          TEXT            NULL,
+    dt_proxima_tentativa DATETIME        NOT NULL,
+    dt_entrega           DATETIME        NULL,
+    dt_cadastro          DATETIME        NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_webhook_entrega (assinatura_id, evento_id),
+    KEY idx_webhook_entrega_pendente (status, dt_proxima_tentativa)
+);
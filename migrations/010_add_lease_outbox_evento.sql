@@ -0,0 +1,4 @@
+-- A dispatcher claims the events it delivers until dt_lease and commits the claim before publishing them, so no row
+-- stays locked while the sink answers. Events whose lease expired, as their dispatcher stopped before recording the
+-- result, are claimed again.
+ALTER TABLE outbox_evento ADD COLUMN dt_lease DATETIME NULL AFTER dt_envio;
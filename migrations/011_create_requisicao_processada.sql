@@ -0,0 +1,9 @@
+-- The tabloid queue and the S3 inbox are delivered at least once. The idempotency key of each message or manifest
+-- whose tabloid was created is written in the transaction of the create, so a redelivery is skipped instead of
+-- creating the tabloid twice.
+CREATE TABLE IF NOT EXISTS requisicao_processada (
+    chave               VARCHAR(512)    NOT NULL,
+    tabloide_id         BIGINT UNSIGNED NOT NULL,
+    dt_cadastro         DATETIME        NOT NULL,
+    PRIMARY KEY (chave)
+);
@@ -0,0 +1,3 @@
+-- A restored tabloid stays expired, so dt_restauracao records when it was brought back from the archive: the archive
+-- job skips it until its validity is extended past the restore and ends again.
+ALTER TABLE tabloide ADD COLUMN dt_restauracao DATETIME NULL AFTER dt_arquivamento;
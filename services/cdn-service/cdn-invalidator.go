@@ -0,0 +1,117 @@
+// Package cdnservice provides the invalidation of the CDN paths made stale by tabloid changes.
+package cdnservice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/google/uuid"
+)
+
+// Invalidator removes paths from the cache of a CDN, so the next requests fetch them from the origin.
+type Invalidator interface {
+	Invalidate(ctx context.Context, paths []string) error
+}
+
+// recordingInvalidator is the Invalidator of CDN_INVALIDATION=memory, shared by the whole process.
+var recordingInvalidator = &RecordingInvalidator{}
+
+// NewInvalidator creates the Invalidator selected by the CDN_INVALIDATION environment variable:
+//
+//   - "cloudfront": invalidates the paths in the CloudFront distribution CLOUDFRONT_DISTRIBUTION_ID.
+//   - "none" (default): does nothing, for CDNs whose cache expires soon enough.
+//   - "memory": records the invalidations in memory, for tests. Every call returns the same RecordingInvalidator,
+//     so a test reads what the handlers invalidated.
+//
+// It returns an error if the configuration is invalid.
+func NewInvalidator() (Invalidator, error) {
+	switch mode := os.Getenv("CDN_INVALIDATION"); mode {
+	case "cloudfront":
+		distributionID := os.Getenv("CLOUDFRONT_DISTRIBUTION_ID")
+		if distributionID == "" {
+			return nil, fmt.Errorf("CDN_INVALIDATION=cloudfront requires CLOUDFRONT_DISTRIBUTION_ID")
+		}
+		// CloudFront is a global service, signed in us-east-1 whatever the region of the function
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion("us-east-1"))
+		if err != nil {
+			return nil, err
+		}
+		return &CloudFrontInvalidator{Client: cloudfront.NewFromConfig(cfg), DistributionID: distributionID}, nil
+	case "", "none":
+		return NoopInvalidator{}, nil
+	case "memory":
+		return recordingInvalidator, nil
+	default:
+		return nil, fmt.Errorf("invalid CDN_INVALIDATION: %q", mode)
+	}
+}
+
+// maxInvalidationPaths is how many paths are sent per invalidation; CloudFront accepts up to 3000 in progress.
+const maxInvalidationPaths = 1000
+
+// CloudFrontInvalidator creates invalidations in a CloudFront distribution.
+type CloudFrontInvalidator struct {
+	Client         *cloudfront.Client
+	DistributionID string
+}
+
+// Invalidate creates one invalidation per maxInvalidationPaths paths. CloudFront processes them asynchronously:
+// a nil error means they were accepted, not that the cache is already clear.
+func (i *CloudFrontInvalidator) Invalidate(ctx context.Context, paths []string) error {
+	for start := 0; start < len(paths); start += maxInvalidationPaths {
+		batch := paths[start:min(start+maxInvalidationPaths, len(paths))]
+		_, err := i.Client.CreateInvalidation(ctx, &cloudfront.CreateInvalidationInput{
+			DistributionId: aws.String(i.DistributionID),
+			InvalidationBatch: &types.InvalidationBatch{
+				CallerReference: aws.String(uuid.New().String()),
+				Paths:           &types.Paths{Quantity: aws.Int32(int32(len(batch))), Items: batch},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to invalidate %d paths: %w", len(batch), err)
+		}
+	}
+	return nil
+}
+
+// NoopInvalidator invalidates nothing.
+type NoopInvalidator struct{}
+
+// Invalidate does nothing.
+func (NoopInvalidator) Invalidate(ctx context.Context, paths []string) error {
+	return nil
+}
+
+// RecordingInvalidator keeps the invalidated paths in memory, for tests.
+type RecordingInvalidator struct {
+	mu            sync.Mutex
+	invalidations [][]string
+}
+
+// Invalidate keeps one batch of paths.
+func (i *RecordingInvalidator) Invalidate(ctx context.Context, paths []string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.invalidations = append(i.invalidations, append([]string(nil), paths...))
+	return nil
+}
+
+// Reset forgets the batches invalidated so far.
+func (i *RecordingInvalidator) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.invalidations = nil
+}
+
+// Invalidations returns the batches of paths invalidated so far, in order.
+func (i *RecordingInvalidator) Invalidations() [][]string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([][]string(nil), i.invalidations...)
+}
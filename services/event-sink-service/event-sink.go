@@ -0,0 +1,122 @@
+// Package eventsinkservice provides the destinations the domain events of the outbox are delivered to.
+package eventsinkservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"test/lambda/interfaces"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// EventSink delivers domain events to other systems.
+type EventSink interface {
+	Publish(ctx context.Context, event interfaces.DomainEvent) error
+}
+
+// NewEventSink creates the EventSink selected by the EVENT_SINK environment variable:
+//
+//   - "sns": publishes to the SNS topic EVENT_TOPIC_ARN.
+//   - "log" (default): prints the events, for running locally.
+//   - "memory": keeps the events in memory, for tests.
+//
+// It returns an error if the configuration is invalid.
+func NewEventSink() (EventSink, error) {
+	switch mode := os.Getenv("EVENT_SINK"); mode {
+	case "sns":
+		topicARN := os.Getenv("EVENT_TOPIC_ARN")
+		if topicARN == "" {
+			return nil, fmt.Errorf("EVENT_SINK=sns requires EVENT_TOPIC_ARN")
+		}
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(os.Getenv("REGION")))
+		if err != nil {
+			return nil, err
+		}
+		return &SNSSink{Client: sns.NewFromConfig(cfg), TopicARN: topicARN}, nil
+	case "", "log":
+		return LogSink{}, nil
+	case "memory":
+		return &MemorySink{}, nil
+	default:
+		return nil, fmt.Errorf("invalid EVENT_SINK: %q", mode)
+	}
+}
+
+// SNSSink publishes events to an SNS topic. The type and the region of the event are sent as message attributes,
+// so subscriptions can filter on them. FIFO topics (".fifo") get the deduplication ID of the event and keep the
+// events of one tabloid in order.
+type SNSSink struct {
+	Client   *sns.Client
+	TopicARN string
+}
+
+// Publish publishes one event to the topic.
+func (s *SNSSink) Publish(ctx context.Context, event interfaces.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	input := &sns.PublishInput{
+		TopicArn: aws.String(s.TopicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"type":             {DataType: aws.String("String"), StringValue: aws.String(event.Type)},
+			"regiao_id":        {DataType: aws.String("Number"), StringValue: aws.String(strconv.Itoa(event.RegionID))},
+			"deduplication_id": {DataType: aws.String("String"), StringValue: aws.String(event.DeduplicationID)},
+		},
+	}
+	if strings.HasSuffix(s.TopicARN, ".fifo") {
+		input.MessageDeduplicationId = aws.String(event.DeduplicationID)
+		input.MessageGroupId = aws.String(strconv.FormatInt(event.TabloidID, 10))
+	}
+
+	if _, err := s.Client.Publish(ctx, input); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", event.DeduplicationID, err)
+	}
+	return nil
+}
+
+// LogSink prints the events instead of delivering them.
+type LogSink struct{}
+
+// Publish prints one event.
+func (LogSink) Publish(ctx context.Context, event interfaces.DomainEvent) error {
+	fmt.Printf("event %s %s tabloid=%d payload=%s\n", event.Type, event.DeduplicationID, event.TabloidID, event.Payload)
+	return nil
+}
+
+// MemorySink keeps the published events in memory, for tests.
+// Events published with a deduplication ID already kept are dropped, as consumers are expected to.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []interfaces.DomainEvent
+}
+
+// Publish keeps one event.
+func (s *MemorySink) Publish(ctx context.Context, event interfaces.DomainEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, kept := range s.events {
+		if kept.DeduplicationID == event.DeduplicationID {
+			return nil
+		}
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns the events kept so far, in publishing order.
+func (s *MemorySink) Events() []interfaces.DomainEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interfaces.DomainEvent(nil), s.events...)
+}
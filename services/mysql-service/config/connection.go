@@ -2,12 +2,15 @@
 package mysql_config
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"os"
+	"sync"
 	awsconfig "test/lambda/aws-config"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MysqlDatabase represents a MySQL database connection.
@@ -20,13 +23,64 @@ func (mysql *MysqlDatabase) GetConn() *sql.DB {
 	return mysql.Db
 }
 
-// NewMysqlDatabase creates a new MySQL database instance.
-// It fetches database credentials from AWS Secrets Manager based on the provided secret ID.
-// It returns a pointer to the mysqlDatabase or panics if an error occurs during initialization.
+// sharedConnector opens the connections of sharedDatabase, the database shared by every repository of the process,
+// so they share one connection pool.
+var (
+	sharedConnector = &secretConnector{}
+	sharedDatabase  = &MysqlDatabase{Db: sql.OpenDB(sharedConnector)}
+)
+
+// NewMysqlDatabase returns the MySQL database instance of the process.
+// The database credentials are fetched from AWS Secrets Manager when the first connection is opened, so it never
+// fails: while the secret cannot be fetched, every query returns that error, and the next one fetches it again.
 func NewMysqlDatabase() *MysqlDatabase {
-	env, err := awsconfig.GetSecret(os.Getenv("SECRET_ID_MYSQL"))
+	return sharedDatabase
+}
+
+// OpenMysqlDatabase returns the MySQL database instance of the process, as NewMysqlDatabase does, once its
+// credentials are fetched from AWS Secrets Manager, giving up when ctx is done.
+// It returns an error if the secret cannot be fetched.
+func OpenMysqlDatabase(ctx context.Context) (*MysqlDatabase, error) {
+	if _, err := sharedConnector.connector(ctx); err != nil {
+		return nil, err
+	}
+	return sharedDatabase, nil
+}
+
+// secretConnector opens MySQL connections with the credentials of the SECRET_ID_MYSQL secret, fetched on first use.
+// A failed fetch is not kept, so the next connection fetches the secret again.
+type secretConnector struct {
+	mu     sync.Mutex
+	opened driver.Connector
+}
+
+// Connect opens a connection, fetching the secret first if needed.
+func (c *secretConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.connector(ctx)
 	if err != nil {
-		fmt.Println("Error fetching secret", err)
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// Driver returns the MySQL driver.
+func (c *secretConnector) Driver() driver.Driver {
+	return &mysql.MySQLDriver{}
+}
+
+// connector returns the connector built from the secret, fetching it within ctx on first use. The lock is not held
+// meanwhile, so a slow fetch does not block the connections of the other callers.
+func (c *secretConnector) connector(ctx context.Context) (driver.Connector, error) {
+	c.mu.Lock()
+	opened := c.opened
+	c.mu.Unlock()
+	if opened != nil {
+		return opened, nil
+	}
+
+	env, err := awsconfig.GetSecretWithContext(ctx, os.Getenv("SECRET_ID_MYSQL"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch database secret: %w", err)
 	}
 
 	dsn := fmt.Sprintf(
@@ -37,11 +91,19 @@ func NewMysqlDatabase() *MysqlDatabase {
 		env["DB_PORT_MYSQL"],
 		env["DB_DATABASE_MYSQL"],
 	)
-
-	db, err := sql.Open("mysql", dsn)
+	config, err := mysql.ParseDSN(dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	opened, err = mysql.NewConnector(config)
+	if err != nil {
+		return nil, err
 	}
 
-	return &MysqlDatabase{Db: db}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opened == nil {
+		c.opened = opened
+	}
+	return c.opened, nil
 }
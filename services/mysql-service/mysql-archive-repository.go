@@ -0,0 +1,101 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// GetTabloidsToArchive retrieves the tabloids not archived yet whose validity ended before the given date.
+// Tabloids restored from the archive after their validity ended are skipped.
+func (r *MysqlTabloideRepository) GetTabloidsToArchive(expiredBefore time.Time) ([]interfaces.Tabloid, error) {
+	query := `SELECT id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao
+		FROM ` + r.tableName + ` WHERE dt_arquivamento IS NULL AND dt_fim_vigencia < ?
+		AND (dt_restauracao IS NULL OR dt_restauracao < dt_fim_vigencia) ORDER BY dt_fim_vigencia, id`
+
+	rows, err := r.connection.Query(query, expiredBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	tabloids := []interfaces.Tabloid{}
+	for rows.Next() {
+		tabloid, err := scanTabloid(rows)
+		if err != nil {
+			return nil, err
+		}
+		tabloids = append(tabloids, *tabloid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return tabloids, nil
+}
+
+// CountImageReferences counts the pages of other tabloids, not archived, that show the image stored under key.
+func (r *MysqlTabloideRepository) CountImageReferences(imageKey string, excludedTabloidID int64) (int, error) {
+	var count int
+	err := r.connection.QueryRow(
+		`SELECT COUNT(*) FROM imagem_tabloide
+		WHERE imagem_key = ? AND tabloide_id <> ? AND (storage_id IS NULL OR storage_id <> ?)`,
+		imageKey, excludedTabloidID, interfaces.StorageIDArchive,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return count, nil
+}
+
+// IsTabloidArchived reports whether a tabloid is archived.
+// It returns an error wrapping sql.ErrNoRows if the tabloid does not exist.
+func (r *MysqlTabloideRepository) IsTabloidArchived(tabloidID int64) (bool, error) {
+	var archived bool
+	err := r.connection.QueryRow(`SELECT dt_arquivamento IS NOT NULL FROM `+r.tableName+` WHERE id = ?`, tabloidID).Scan(&archived)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query: %w", err)
+	}
+	return archived, nil
+}
+
+// SetTabloidArchived marks a tabloid as archived, or as restored from the archive, and moves its pages to the given
+// storage backend.
+func (r *MysqlTabloideRepository) SetTabloidArchived(tabloidID int64, archived bool, storageID string, transaction *sql.Tx) error {
+	query := `UPDATE ` + r.tableName + ` SET dt_arquivamento = NULL, dt_restauracao = NOW() WHERE id = ?`
+	if archived {
+		query = `UPDATE ` + r.tableName + ` SET dt_arquivamento = NOW() WHERE id = ?`
+	}
+	if _, err := transaction.Exec(query, tabloidID); err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+
+	_, err := transaction.Exec(`UPDATE imagem_tabloide SET storage_id = ? WHERE tabloide_id = ?`, storageID, tabloidID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetArchivedImageKeys retrieves the keys of the images of archived pages.
+func (r *MysqlTabloideRepository) GetArchivedImageKeys() (map[string]bool, error) {
+	rows, err := r.connection.Query(`SELECT DISTINCT imagem_key FROM imagem_tabloide WHERE storage_id = ? AND imagem_key IS NOT NULL`,
+		interfaces.StorageIDArchive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		keys[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,105 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+	"test/lambda/interfaces"
+)
+
+// InsertAuditEntry inserts an audit entry into the auditoria_tabloide table.
+// It takes the entry and a transaction object, so the entry is only persisted when the mutation it describes is committed.
+// It returns an error if the operation fails.
+//
+// Example:
+//
+//	entry := interfaces.AuditEntry{
+//	    TabloidID: tabloidID,
+//	    Entity:    interfaces.AuditEntityTabloid,
+//	    Action:    interfaces.AuditActionCreate,
+//	    Actor:     "marcos",
+//	    RequestID: "c1b2...",
+//	    After:     json.RawMessage(`{"nome":"Sample Tabloid"}`),
+//	}
+//	err := repository.InsertAuditEntry(entry, transaction)
+//	if err != nil {
+//	    log.Fatalf("Failed to insert audit entry: %v", err)
+//	}
+func (r *MysqlTabloideRepository) InsertAuditEntry(entry interfaces.AuditEntry, transaction *sql.Tx) error {
+	query :=
+		`INSERT INTO auditoria_tabloide
+		(tabloide_id, entidade, acao, usuario, request_id, valor_anterior, valor_novo, dt_cadastro)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())`
+
+	_, err := transaction.Exec(query, entry.TabloidID, entry.Entity, entry.Action, entry.Actor, entry.RequestID,
+		nullableJSON(entry.Before), nullableJSON(entry.After))
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetAuditEntriesByTabloidId retrieves the audit history of a tabloid, most recent entry first.
+// It returns an empty slice if the tabloid has no history.
+//
+// Example:
+//
+//	entries, err := repository.GetAuditEntriesByTabloidId(42)
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve audit history: %v", err)
+//	}
+//	for _, entry := range entries {
+//	    fmt.Println(entry.CreatedAt, entry.Actor, entry.Action, entry.Entity)
+//	}
+func (r *MysqlTabloideRepository) GetAuditEntriesByTabloidId(tabloidID int64) ([]interfaces.AuditEntry, error) {
+	query :=
+		`SELECT id, tabloide_id, entidade, acao, usuario, request_id, valor_anterior, valor_novo, dt_cadastro
+		FROM auditoria_tabloide
+		WHERE tabloide_id = ?
+		ORDER BY dt_cadastro DESC, id DESC`
+
+	rows, err := r.connection.Query(query, tabloidID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []interfaces.AuditEntry{}
+	for rows.Next() {
+		var entry interfaces.AuditEntry
+		var before, after sql.NullString
+		var dtCadastro []uint8
+
+		err := rows.Scan(&entry.ID, &entry.TabloidID, &entry.Entity, &entry.Action, &entry.Actor, &entry.RequestID,
+			&before, &after, &dtCadastro)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		if before.Valid {
+			entry.Before = []byte(before.String)
+		}
+		if after.Valid {
+			entry.After = []byte(after.String)
+		}
+
+		entry.CreatedAt, err = parseDateTime(dtCadastro)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+		}
+
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	return entries, nil
+}
+
+// nullableJSON converts an empty JSON value into a SQL NULL.
+func nullableJSON(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
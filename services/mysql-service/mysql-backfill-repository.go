@@ -0,0 +1,141 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"test/lambda/interfaces"
+)
+
+// GetPagesWithoutImageKey retrieves the pages stored before image keys were introduced, which only have a URL.
+func (r *MysqlTabloideRepository) GetPagesWithoutImageKey() ([]interfaces.Page, error) {
+	rows, err := r.connection.Query(pageColumns + ` WHERE imagem_key IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var pages []interfaces.Page
+	for rows.Next() {
+		page, err := scanPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return pages, nil
+}
+
+// UpdatePageImageKey sets the key and the storage backend of a page stored with a URL only.
+func (r *MysqlTabloideRepository) UpdatePageImageKey(pageID int64, imageKey, storageID string, transaction *sql.Tx) error {
+	_, err := transaction.Exec(`UPDATE imagem_tabloide SET imagem_key = ?, storage_id = ? WHERE id = ?`, imageKey, storageID, pageID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// BackfillVariantKeys derives the keys of the resized copies stored with URLs only, stripping the given URL prefix.
+// Copies whose URLs do not start with the prefix are left untouched.
+// It returns the number of converted copies.
+func (r *MysqlTabloideRepository) BackfillVariantKeys(prefix string, transaction *sql.Tx) (int64, error) {
+	query :=
+		`UPDATE variante_imagem_tabloide
+		SET imagem_key = SUBSTRING(imagem_url, CHAR_LENGTH(?) + 1), variante_key = SUBSTRING(variante_url, CHAR_LENGTH(?) + 1)
+		WHERE imagem_key IS NULL AND LEFT(imagem_url, CHAR_LENGTH(?)) = ? AND LEFT(variante_url, CHAR_LENGTH(?)) = ?`
+
+	result, err := transaction.Exec(query, prefix, prefix, prefix, prefix, prefix, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetAllTabloidVersions retrieves the ID, number and pages of every stored version, to rewrite their pages.
+// Only those fields are filled.
+func (r *MysqlTabloideRepository) GetAllTabloidVersions() ([]interfaces.TabloidVersion, error) {
+	rows, err := r.connection.Query(`SELECT id, tabloide_id, versao, paginas FROM versao_tabloide ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []interfaces.TabloidVersion
+	for rows.Next() {
+		var version interfaces.TabloidVersion
+		var pages []byte
+		if err := rows.Scan(&version.ID, &version.TabloidID, &version.Version, &pages); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if err := json.Unmarshal(pages, &version.Pages); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal pages: %v", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return versions, nil
+}
+
+// UpdateTabloidVersionPages rewrites the pages of a stored version.
+// It is only meant for data migrations; versions are otherwise immutable.
+func (r *MysqlTabloideRepository) UpdateTabloidVersionPages(versionID int64, pages []string, transaction *sql.Tx) error {
+	encoded, err := json.Marshal(pages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pages: %v", err)
+	}
+	_, err = transaction.Exec(`UPDATE versao_tabloide SET paginas = ? WHERE id = ?`, string(encoded), versionID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetPagesWithImageKey retrieves every page stored with a key, with its resized copies.
+func (r *MysqlTabloideRepository) GetPagesWithImageKey() ([]interfaces.Page, error) {
+	rows, err := r.connection.Query(pageColumns + ` WHERE imagem_key IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var pages []interfaces.Page
+	for rows.Next() {
+		page, err := scanPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	if err := r.attachVariants(pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// RenameImageKey points every page and resized copy stored under oldKey to newKey.
+// variants are the resized copies under their new keys.
+func (r *MysqlTabloideRepository) RenameImageKey(oldKey, newKey string, variants []interfaces.PageVariant, transaction *sql.Tx) error {
+	_, err := transaction.Exec(`UPDATE imagem_tabloide SET imagem_key = ? WHERE imagem_key = ?`, newKey, oldKey)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+
+	for _, variant := range variants {
+		_, err := transaction.Exec(
+			`UPDATE variante_imagem_tabloide SET imagem_key = ?, variante_key = ? WHERE imagem_key = ? AND largura = ?`,
+			newKey, variant.Key, oldKey, variant.Width)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %v", err)
+		}
+	}
+	return nil
+}
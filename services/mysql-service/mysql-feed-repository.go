@@ -0,0 +1,106 @@
+package mysqlservice
+
+import (
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// RegionExists reports whether a region with the given ID exists.
+func (r *MysqlTabloideRepository) RegionExists(regionID int) (bool, error) {
+	var count int
+	if err := r.connection.QueryRow("SELECT COUNT(*) FROM regiao WHERE id = ?", regionID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetActiveTabloidsByRegion retrieves the active tabloids of a region whose validity ends on or after the given date,
+// ordered by the start of their validity. The result includes tabloids that are not valid yet, so callers can
+// tell when the next one starts.
+//
+// Example:
+//
+//	today := time.Now().UTC().Truncate(24 * time.Hour)
+//	tabloids, err := repository.GetActiveTabloidsByRegion(144, today)
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve tabloids: %v", err)
+//	}
+func (r *MysqlTabloideRepository) GetActiveTabloidsByRegion(regionID int, from time.Time) ([]interfaces.Tabloid, error) {
+	query := `SELECT id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao
+		FROM ` + r.tableName + `
+		WHERE regiao_id = ? AND ativo = 1 AND dt_fim_vigencia >= ?
+		ORDER BY dt_inicio_vigencia, id`
+
+	rows, err := r.connection.Query(query, regionID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	tabloids := []interfaces.Tabloid{}
+	for rows.Next() {
+		tabloid, err := scanTabloid(rows)
+		if err != nil {
+			return nil, err
+		}
+		tabloids = append(tabloids, *tabloid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	return tabloids, nil
+}
+
+// GetPagesByTabloidIds retrieves the pages of several tabloids, along with the resized copies of their images.
+// It returns the pages grouped by tabloid ID, each group ordered by page position.
+//
+// Example:
+//
+//	pagesByTabloid, err := repository.GetPagesByTabloidIds([]int64{41, 42})
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve pages: %v", err)
+//	}
+//	fmt.Println(len(pagesByTabloid[42]), "pages in tabloid 42")
+func (r *MysqlTabloideRepository) GetPagesByTabloidIds(tabloidIDs []int64) (map[int64][]interfaces.Page, error) {
+	pagesByTabloid := map[int64][]interfaces.Page{}
+	if len(tabloidIDs) == 0 {
+		return pagesByTabloid, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tabloidIDs)), ", ")
+	args := make([]interface{}, 0, len(tabloidIDs))
+	for _, tabloidID := range tabloidIDs {
+		args = append(args, tabloidID)
+	}
+
+	query := pageColumns + ` WHERE tabloide_id IN (` + placeholders + `) ORDER BY tabloide_id, ordem, id`
+
+	rows, err := r.connection.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var pages []interfaces.Page
+	for rows.Next() {
+		page, err := scanPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	if err := r.attachVariants(pages); err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		pagesByTabloid[page.TabloidID] = append(pagesByTabloid[page.TabloidID], page)
+	}
+	return pagesByTabloid, nil
+}
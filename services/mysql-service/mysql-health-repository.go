@@ -0,0 +1,31 @@
+package mysqlservice
+
+import (
+	"context"
+	"fmt"
+	"test/lambda/interfaces"
+)
+
+// Ping checks that the database can be reached, opening a connection if none is idle.
+func (r *MysqlTabloideRepository) Ping(ctx context.Context) error {
+	if err := r.connection.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+	return nil
+}
+
+// PoolStats reports the connection pool of the database, shared by every repository of the process.
+func (r *MysqlTabloideRepository) PoolStats() interfaces.PoolStats {
+	stats := r.connection.Stats()
+	return interfaces.PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,30 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetProcessedRequest retrieves the ID of the tabloid created for the request with the given idempotency key.
+// It returns an error wrapping sql.ErrNoRows if the request was not processed.
+func (r *MysqlTabloideRepository) GetProcessedRequest(key string) (int64, error) {
+	var tabloidID int64
+	err := r.connection.QueryRow(`SELECT tabloide_id FROM requisicao_processada WHERE chave = ?`, key).Scan(&tabloidID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %w", err)
+	}
+	return tabloidID, nil
+}
+
+// InsertProcessedRequest records that the request with the given idempotency key created a tabloid, inside the
+// transaction of the create. A request processed twice concurrently fails on the primary key, so only one commits.
+func (r *MysqlTabloideRepository) InsertProcessedRequest(key string, tabloidID int64, transaction *sql.Tx) error {
+	_, err := transaction.Exec(
+		`INSERT INTO requisicao_processada (chave, tabloide_id, dt_cadastro) VALUES (?, ?, NOW())`,
+		key, tabloidID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
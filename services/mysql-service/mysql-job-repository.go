@@ -0,0 +1,172 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// InsertJob stores a new job in the job_tabloide table, with the queued status and no pages processed.
+//
+// Example:
+//
+//	job := interfaces.Job{
+//	    ID:        uuid.New().String(),
+//	    Request:   formData,
+//	    FileKey:   "jobs/0b5f.../tabloide.zip",
+//	    Actor:     "marcos",
+//	    RequestID: auditContext.RequestID,
+//	}
+//	if err := repository.InsertJob(job); err != nil {
+//	    log.Fatalf("Failed to insert job: %v", err)
+//	}
+func (r *MysqlTabloideRepository) InsertJob(job interfaces.Job) error {
+	request, err := json.Marshal(job.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal requisicao: %v", err)
+	}
+
+	query :=
+		`INSERT INTO job_tabloide
+		(id, status, paginas, requisicao, arquivo_key, usuario, request_id, dt_cadastro, dt_alteracao)
+		VALUES (?, ?, '[]', ?, ?, ?, ?, NOW(), NOW())`
+
+	_, err = r.connection.Exec(query, job.ID, interfaces.JobStatusQueued, string(request), job.FileKey, job.Actor, job.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetJob retrieves a job by its ID.
+// It returns an error wrapping sql.ErrNoRows if the job does not exist.
+//
+// Example:
+//
+//	job, err := repository.GetJob(jobID)
+//	if errors.Is(err, sql.ErrNoRows) {
+//	    fmt.Println("Job not found")
+//	}
+func (r *MysqlTabloideRepository) GetJob(jobID string) (*interfaces.Job, error) {
+	var job interfaces.Job
+	var tabloidID sql.NullInt64
+	var pages, request string
+	var jobError, code sql.NullString
+	var dtCadastro, dtAlteracao []uint8
+
+	err := r.connection.QueryRow(
+		`SELECT id, status, tabloide_id, total_paginas, paginas, erro, codigo, requisicao, arquivo_key, usuario, request_id,
+		dt_cadastro, dt_alteracao FROM job_tabloide WHERE id = ? LIMIT 1`, jobID,
+	).Scan(&job.ID, &job.Status, &tabloidID, &job.TotalPages, &pages, &jobError, &code, &request, &job.FileKey,
+		&job.Actor, &job.RequestID, &dtCadastro, &dtAlteracao)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	if tabloidID.Valid {
+		job.TabloidID = &tabloidID.Int64
+	}
+	job.Error = jobError.String
+	job.Code = code.String
+	if err := json.Unmarshal([]byte(pages), &job.Pages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal paginas: %v", err)
+	}
+	job.ProcessedPages = len(job.Pages)
+	if err := json.Unmarshal([]byte(request), &job.Request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal requisicao: %v", err)
+	}
+	if job.CreatedAt, err = parseDateTime(dtCadastro); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+	}
+	if job.UpdatedAt, err = parseDateTime(dtAlteracao); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_alteracao: %v", err)
+	}
+
+	return &job, nil
+}
+
+// UpdateJobStatus changes the status of a job, recording why it failed when status is failed.
+func (r *MysqlTabloideRepository) UpdateJobStatus(jobID, status, jobError, code string) error {
+	_, err := r.connection.Exec(
+		`UPDATE job_tabloide SET status = ?, erro = NULLIF(?, ''), codigo = NULLIF(?, ''), dt_alteracao = NOW() WHERE id = ?`,
+		status, jobError, code, jobID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// ClaimJob takes a queued job for one run, marking it as running, so concurrent deliveries of its message do not
+// both create its tabloid. A job left running longer than lease since its last change, by a consumer that crashed,
+// is claimed again. It returns false when the job is not queued, or is running elsewhere.
+func (r *MysqlTabloideRepository) ClaimJob(jobID string, lease time.Duration) (bool, error) {
+	result, err := r.connection.Exec(
+		`UPDATE job_tabloide SET status = ?, dt_alteracao = NOW()
+		WHERE id = ? AND (status = ? OR (status = ? AND dt_alteracao <= DATE_SUB(NOW(), INTERVAL ? SECOND)))`,
+		interfaces.JobStatusRunning, jobID, interfaces.JobStatusQueued, interfaces.JobStatusRunning, int64(lease.Seconds()),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ReleaseJob ends the run of a claimed job with status: queued to run it again, or failed, recording why.
+// A job that succeeded meanwhile, in a run that claimed it again after its lease expired, is left as is.
+func (r *MysqlTabloideRepository) ReleaseJob(jobID, status, jobError, code string) error {
+	_, err := r.connection.Exec(
+		`UPDATE job_tabloide SET status = ?, erro = NULLIF(?, ''), codigo = NULLIF(?, ''), dt_alteracao = NOW()
+		WHERE id = ? AND status = ?`,
+		status, jobError, code, jobID, interfaces.JobStatusRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// UpdateJobPages records the total number of pages of a job and the result of the pages processed so far.
+func (r *MysqlTabloideRepository) UpdateJobPages(jobID string, totalPages int, pages []interfaces.JobPage) error {
+	data, err := json.Marshal(pages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal paginas: %v", err)
+	}
+
+	_, err = r.connection.Exec(
+		`UPDATE job_tabloide SET total_paginas = ?, paginas = ?, dt_alteracao = NOW() WHERE id = ?`,
+		totalPages, string(data), jobID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// CompleteJob marks a job as succeeded with the tabloid it created.
+// It runs inside the transaction that creates the tabloid and fails if the job already succeeded, so a job never
+// creates its tabloid twice, even when a run claimed it again after its lease expired.
+func (r *MysqlTabloideRepository) CompleteJob(jobID string, tabloidID int64, transaction *sql.Tx) error {
+	result, err := transaction.Exec(
+		`UPDATE job_tabloide SET status = ?, tabloide_id = ?, erro = NULL, codigo = NULL, dt_alteracao = NOW()
+		WHERE id = ? AND status <> ?`,
+		interfaces.JobStatusSucceeded, tabloidID, jobID, interfaces.JobStatusSucceeded,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("job %s already succeeded or does not exist", jobID)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// InsertOutboxEvent writes a domain event to the outbox_evento table, inside the transaction of the change it describes.
+// Events whose deduplication ID is already in the outbox are ignored, so writing the same event twice is harmless.
+//
+// Example:
+//
+//	event := interfaces.DomainEvent{
+//	    DeduplicationID: "tabloide-42-created",
+//	    Type:            interfaces.EventTabloidCreated,
+//	    TabloidID:       42,
+//	    RegionID:        144,
+//	    Payload:         payload,
+//	}
+//	if err := repository.InsertOutboxEvent(event, transaction); err != nil {
+//	    log.Fatalf("Failed to write event: %v", err)
+//	}
+func (r *MysqlTabloideRepository) InsertOutboxEvent(event interfaces.DomainEvent, transaction *sql.Tx) error {
+	_, err := transaction.Exec(
+		`INSERT IGNORE INTO outbox_evento (id_deduplicacao, tipo, tabloide_id, regiao_id, payload, dt_cadastro)
+		VALUES (?, ?, ?, ?, ?, NOW())`,
+		event.DeduplicationID, event.Type, event.TabloidID, event.RegionID, string(event.Payload),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// ClaimPendingEvents claims up to limit events not delivered yet, oldest first, for lease, so the transaction can be
+// committed before they are published. Events that failed maxAttempts times, events claimed by another dispatcher
+// whose lease has not expired and rows locked by a dispatcher claiming at the same time are skipped.
+func (r *MysqlTabloideRepository) ClaimPendingEvents(limit, maxAttempts int, lease time.Duration, transaction *sql.Tx) ([]interfaces.DomainEvent, error) {
+	events, err := r.getPendingEvents(limit, maxAttempts, transaction)
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	args := []any{int64(lease.Seconds())}
+	for _, event := range events {
+		args = append(args, event.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(events)), ", ")
+	_, err = transaction.Exec(
+		`UPDATE outbox_evento SET dt_lease = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE id IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return events, nil
+}
+
+// getPendingEvents locks up to limit pending events that are not leased, skipping those locked by another
+// dispatcher. The locks are held until the transaction ends.
+func (r *MysqlTabloideRepository) getPendingEvents(limit, maxAttempts int, transaction *sql.Tx) ([]interfaces.DomainEvent, error) {
+	rows, err := transaction.Query(
+		`SELECT id, id_deduplicacao, tipo, tabloide_id, regiao_id, payload, tentativas, dt_cadastro FROM outbox_evento
+		WHERE dt_envio IS NULL AND tentativas < ? AND (dt_lease IS NULL OR dt_lease <= NOW())
+		ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED`,
+		maxAttempts, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	events := []interfaces.DomainEvent{}
+	for rows.Next() {
+		var event interfaces.DomainEvent
+		var payload string
+		var dtCadastro []uint8
+		err := rows.Scan(&event.ID, &event.DeduplicationID, &event.Type, &event.TabloidID, &event.RegionID, &payload,
+			&event.Attempts, &dtCadastro)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		event.Payload = []byte(payload)
+		if event.OccurredAt, err = parseDateTime(dtCadastro); err != nil {
+			return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+	return events, nil
+}
+
+// MarkEventSent records that an event was delivered.
+func (r *MysqlTabloideRepository) MarkEventSent(eventID int64, transaction *sql.Tx) error {
+	_, err := transaction.Exec(`UPDATE outbox_evento SET dt_envio = NOW(), dt_lease = NULL, ultimo_erro = NULL WHERE id = ?`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// MarkEventFailed records a failed delivery attempt of an event and its error.
+func (r *MysqlTabloideRepository) MarkEventFailed(eventID int64, deliveryError string, transaction *sql.Tx) error {
+	_, err := transaction.Exec(`UPDATE outbox_evento SET tentativas = tentativas + 1, dt_lease = NULL, ultimo_erro = ? WHERE id = ?`,
+		deliveryError, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetTabloidsStartedBetween retrieves the active tabloids whose validity started in [from, to].
+func (r *MysqlTabloideRepository) GetTabloidsStartedBetween(from, to time.Time) ([]interfaces.Tabloid, error) {
+	return r.queryTabloids(`SELECT id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao
+		FROM `+r.tableName+` WHERE ativo = 1 AND dt_inicio_vigencia BETWEEN ? AND ? ORDER BY id`, from, to)
+}
+
+// GetTabloidsEndedBetween retrieves the active tabloids whose validity ended in [from, to].
+func (r *MysqlTabloideRepository) GetTabloidsEndedBetween(from, to time.Time) ([]interfaces.Tabloid, error) {
+	return r.queryTabloids(`SELECT id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao
+		FROM `+r.tableName+` WHERE ativo = 1 AND dt_fim_vigencia BETWEEN ? AND ? ORDER BY id`, from, to)
+}
+
+// queryTabloids retrieves the tabloids selected by a query of the columns read by scanTabloid.
+func (r *MysqlTabloideRepository) queryTabloids(query string, args ...any) ([]interfaces.Tabloid, error) {
+	rows, err := r.connection.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	tabloids := []interfaces.Tabloid{}
+	for rows.Next() {
+		tabloid, err := scanTabloid(rows)
+		if err != nil {
+			return nil, err
+		}
+		tabloids = append(tabloids, *tabloid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return tabloids, nil
+}
@@ -0,0 +1,71 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+)
+
+// GetStoredImageReferences retrieves the image of every page and resized copy kept in the page bucket: its key,
+// or its URL for rows not converted by the backfill yet. Pages of archived tabloids are kept in the archive instead.
+func (r *MysqlTabloideRepository) GetStoredImageReferences() ([]string, error) {
+	query :=
+		`SELECT COALESCE(imagem_key, imagem_url) FROM imagem_tabloide WHERE storage_id IS NULL OR storage_id <> ?
+		UNION SELECT COALESCE(v.variante_key, v.variante_url) FROM variante_imagem_tabloide v
+		WHERE EXISTS (SELECT 1 FROM imagem_tabloide p
+			WHERE (p.imagem_key = v.imagem_key OR p.imagem_url = v.imagem_url) AND (p.storage_id IS NULL OR p.storage_id <> ?))`
+
+	rows, err := r.connection.Query(query, interfaces.StorageIDArchive, interfaces.StorageIDArchive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var references []string
+	for rows.Next() {
+		var reference sql.NullString
+		if err := rows.Scan(&reference); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if reference.Valid {
+			references = append(references, reference.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+	return references, nil
+}
+
+// IsStoredImageReferenced reports whether a committed page or resized copy kept in the page bucket references the
+// object stored under key. Content-addressed keys may be reused by any upload, so it is checked right before such an
+// object is deleted, not only when the references are listed.
+func (r *MysqlTabloideRepository) IsStoredImageReferenced(key string) (bool, error) {
+	var referenced bool
+	err := r.connection.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM imagem_tabloide WHERE imagem_key = ? AND (storage_id IS NULL OR storage_id <> ?))
+		OR EXISTS (SELECT 1 FROM variante_imagem_tabloide v JOIN imagem_tabloide p ON p.imagem_key = v.imagem_key
+			WHERE v.variante_key = ? AND (p.storage_id IS NULL OR p.storage_id <> ?))`,
+		key, interfaces.StorageIDArchive, key, interfaces.StorageIDArchive,
+	).Scan(&referenced)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return referenced, nil
+}
+
+// IsPageBaseReferenced reports whether a committed page, archived or not, is stored under base followed by an
+// extension, as the original of a page is matched by the key of the page without extension.
+func (r *MysqlTabloideRepository) IsPageBaseReferenced(base string) (bool, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(base) + ".%"
+	var referenced bool
+	err := r.connection.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM imagem_tabloide WHERE imagem_key LIKE ?)`,
+		pattern,
+	).Scan(&referenced)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return referenced, nil
+}
@@ -2,6 +2,7 @@
 package mysqlservice
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"test/lambda/interfaces"
@@ -17,9 +18,30 @@ type MysqlTabloideRepository struct {
 
 // NewMysqlTabloideRepository creates a new instance of MysqlTabloideRepository.
 // It initializes a connection to the MySQL database using the configuration from mysql_config package.
-// It returns a pointer to the MysqlTabloideRepository.
+// It returns a pointer to the MysqlTabloideRepository; while the database secret cannot be fetched, its operations
+// return that error.
 func NewMysqlTabloideRepository() *MysqlTabloideRepository {
-	c := mysqlconfig.NewMysqlDatabase().GetConn()
+	return newMysqlTabloideRepository(mysqlconfig.NewMysqlDatabase())
+}
+
+// NewMysqlTabloideRepositoryWithContext creates a MysqlTabloideRepository like NewMysqlTabloideRepository, once the
+// database secret is fetched within ctx. It returns an error if the secret cannot be fetched.
+func NewMysqlTabloideRepositoryWithContext(ctx context.Context) (*MysqlTabloideRepository, error) {
+	database, err := mysqlconfig.OpenMysqlDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newMysqlTabloideRepository(database), nil
+}
+
+// NewMysqlTabloideRepositoryWithDB creates a MysqlTabloideRepository on an open connection, such as a mock in tests.
+func NewMysqlTabloideRepositoryWithDB(connection *sql.DB) *MysqlTabloideRepository {
+	return newMysqlTabloideRepository(&mysqlconfig.MysqlDatabase{Db: connection})
+}
+
+// newMysqlTabloideRepository creates a MysqlTabloideRepository on an open database.
+func newMysqlTabloideRepository(database *mysqlconfig.MysqlDatabase) *MysqlTabloideRepository {
+	c := database.GetConn()
 	tableName := "tabloide"
 	return &MysqlTabloideRepository{
 		connection: c,
@@ -76,8 +98,10 @@ func (r *MysqlTabloideRepository) InsertTabloid(name string, regionID int, start
 	return lastID, nil
 }
 
-// InsertTabloidImage inserts an image URL associated with a tabloid into the database.
-// It takes imageURL, tabloidID, order as input parameters and a transaction object for performing the insert operation as part of a larger transaction.
+// InsertTabloidImage inserts a page image associated with a tabloid into the database.
+// It takes the page (its ImageKey, StorageID, ChecksumSHA256, TabloidID and Order) and a transaction object
+// for performing the insert operation as part of a larger transaction.
+// Only the object key is stored; the URL of the image is built when the page is read.
 // It returns an error if the operation fails.
 //
 // Example:
@@ -89,11 +113,15 @@ func (r *MysqlTabloideRepository) InsertTabloid(name string, regionID int, start
 //	}
 //	defer transaction.Rollback()
 //
-//	imageURL := "https://example.com/image.jpg"
-//	tabloidID := 1
-//	order := 1
+//	page := interfaces.Page{
+//	    ImageKey:       "RPA/v3/1/campanha-1-2b1f...-pagina-1.jpeg",
+//	    StorageID:      "s3",
+//	    ChecksumSHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//	    TabloidID:      1,
+//	    Order:          0,
+//	}
 //
-//	err := repository.InsertTabloidImage(imageURL, tabloidID, order, transaction)
+//	err := repository.InsertTabloidImage(page, transaction)
 //	if err != nil {
 //	    log.Fatalf("Failed to insert tabloid image: %v", err)
 //	}
@@ -103,27 +131,22 @@ func (r *MysqlTabloideRepository) InsertTabloid(name string, regionID int, start
 //	if err != nil {
 //	    log.Fatalf("Failed to commit transaction: %v", err)
 //	}
-func (r *MysqlTabloideRepository) InsertTabloidImage(imageURL string, tabloidID int64, order int, transaction *sql.Tx) error {
+func (r *MysqlTabloideRepository) InsertTabloidImage(page interfaces.Page, transaction *sql.Tx) error {
 	query :=
 		`INSERT INTO imagem_tabloide 
-		(imagem_url, tabloide_id, ordem, dt_cadastro) 
-		VALUES ( ?, ?, ?, NOW())`
+		(imagem_key, storage_id, checksum_sha256, tabloide_id, ordem, dt_cadastro) 
+		VALUES ( ?, ?, ?, ?, ?, NOW())`
 
-	result, err := transaction.Exec(query, imageURL, tabloidID, order)
+	_, err := transaction.Exec(query, page.ImageKey, page.StorageID, nullableString(page.ChecksumSHA256), page.TabloidID, page.Order)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %v", err)
 	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
-	}
-	fmt.Println("Rows affected:", rowsAffected)
 	return nil
 }
 
 // GetRegionById retrieves a region from the database by its ID.
 // It takes regionID as input parameter and returns the corresponding region object or an error if the operation fails.
+// The error wraps sql.ErrNoRows if the region does not exist.
 //
 // Example:
 //
@@ -144,7 +167,7 @@ func (r *MysqlTabloideRepository) GetRegionById(regionID int) (*interfaces.Regio
 
 	err := r.connection.QueryRow(query, regionID).Scan(&region.ID, &region.Nome, &dtCadastro, &dtAlteracao)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %v", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
 	dtCadastroTime, err := time.Parse("2006-01-02 15:04:05", string(dtCadastro))
@@ -162,6 +185,158 @@ func (r *MysqlTabloideRepository) GetRegionById(regionID int) (*interfaces.Regio
 	return &region, nil
 }
 
+// GetTabloidById retrieves a tabloid from the database by its ID.
+// It returns the corresponding tabloid object or an error if the operation fails.
+//
+// Example:
+//
+//	repository := NewMysqlTabloideRepository()
+//
+//	tabloid, err := repository.GetTabloidById(42)
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve tabloid: %v", err)
+//	}
+//	fmt.Printf("Tabloid %s valid from %s to %s\n", tabloid.Nome,
+//	    tabloid.DtInicioVigencia.Format("2006-01-02"), tabloid.DtFimVigencia.Format("2006-01-02"))
+func (r *MysqlTabloideRepository) GetTabloidById(tabloidID int64) (*interfaces.Tabloid, error) {
+	query := `SELECT id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao
+		FROM ` + r.tableName + ` WHERE id = ? LIMIT 1`
+
+	return scanTabloid(r.connection.QueryRow(query, tabloidID))
+}
+
+// GetTabloidPages retrieves the pages of a tabloid ordered by their position, along with the resized copies of their images.
+// It returns an empty slice if the tabloid has no pages.
+//
+// Example:
+//
+//	pages, err := repository.GetTabloidPages(42)
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve pages: %v", err)
+//	}
+//	for _, page := range pages {
+//	    fmt.Println(page.Order, page.ImageURL)
+//	}
+func (r *MysqlTabloideRepository) GetTabloidPages(tabloidID int64) ([]interfaces.Page, error) {
+	query := pageColumns + ` WHERE tabloide_id = ? ORDER BY ordem, id`
+
+	rows, err := r.connection.Query(query, tabloidID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	pages := []interfaces.Page{}
+	for rows.Next() {
+		page, err := scanPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	if err := r.attachVariants(pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// UpdateTabloid updates the metadata of an existing tabloid and sets dt_alteracao to the current time.
+// It takes the tabloid with the new values and a transaction object for performing the update as part of a larger transaction.
+// It returns an error if the operation fails.
+//
+// Example:
+//
+//	tabloid.DtFimVigencia = tabloid.DtFimVigencia.AddDate(0, 0, 7) // Extend validity by one week
+//	err := repository.UpdateTabloid(*tabloid, transaction)
+//	if err != nil {
+//	    log.Fatalf("Failed to update tabloid: %v", err)
+//	}
+func (r *MysqlTabloideRepository) UpdateTabloid(tabloid interfaces.Tabloid, transaction *sql.Tx) error {
+	query := `UPDATE ` + r.tableName + `
+		SET nome = ?, regiao_id = ?, dt_inicio_vigencia = ?, dt_fim_vigencia = ?, ativo = ?, dt_alteracao = NOW()
+		WHERE id = ?`
+
+	_, err := transaction.Exec(query, tabloid.Nome, tabloid.RegiaoID, tabloid.DtInicioVigencia, tabloid.DtFimVigencia, tabloid.Ativo, tabloid.ID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// DeleteTabloidImages removes every page of a tabloid from the database.
+// The images themselves are kept in the S3 bucket.
+// It returns an error if the operation fails.
+//
+// Example:
+//
+//	err := repository.DeleteTabloidImages(42, transaction)
+//	if err != nil {
+//	    log.Fatalf("Failed to delete pages: %v", err)
+//	}
+func (r *MysqlTabloideRepository) DeleteTabloidImages(tabloidID int64, transaction *sql.Tx) error {
+	_, err := transaction.Exec(`DELETE FROM imagem_tabloide WHERE tabloide_id = ?`, tabloidID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// pageColumns selects the columns read by scanPage.
+const pageColumns = `SELECT id, tabloide_id, imagem_url, imagem_key, storage_id, checksum_sha256, ordem FROM imagem_tabloide`
+
+// scanPage scans an imagem_tabloide row selected with pageColumns.
+// Rows not converted by the backfill yet have no key and keep their stored URL.
+func scanPage(row interface{ Scan(dest ...any) error }) (interfaces.Page, error) {
+	var page interfaces.Page
+	var imageURL, imageKey, storageID, checksum sql.NullString
+	if err := row.Scan(&page.ID, &page.TabloidID, &imageURL, &imageKey, &storageID, &checksum, &page.Order); err != nil {
+		return page, fmt.Errorf("failed to scan row: %v", err)
+	}
+	page.ImageKey = imageKey.String
+	page.StorageID = storageID.String
+	page.ChecksumSHA256 = checksum.String
+	if page.ImageKey == "" {
+		page.ImageURL = imageURL.String
+	}
+	return page, nil
+}
+
+// nullableString converts an empty string into a SQL NULL.
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// scanTabloid scans a tabloide row selected with the columns
+// id, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, dt_cadastro, dt_alteracao.
+func scanTabloid(row interface{ Scan(dest ...any) error }) (*interfaces.Tabloid, error) {
+	var tabloid interfaces.Tabloid
+	var dtInicioVigencia, dtFimVigencia, dtCadastro, dtAlteracao []uint8
+
+	err := row.Scan(&tabloid.ID, &tabloid.Nome, &tabloid.RegiaoID, &dtInicioVigencia, &dtFimVigencia, &tabloid.Ativo, &dtCadastro, &dtAlteracao)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	if tabloid.DtInicioVigencia, err = parseDateTime(dtInicioVigencia); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_inicio_vigencia: %v", err)
+	}
+	if tabloid.DtFimVigencia, err = parseDateTime(dtFimVigencia); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_fim_vigencia: %v", err)
+	}
+	if tabloid.DtCadastro, err = parseDateTime(dtCadastro); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+	}
+	if tabloid.DtAlteracao, err = parseDateTime(dtAlteracao); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_alteracao: %v", err)
+	}
+
+	return &tabloid, nil
+}
+
 func (r *MysqlTabloideRepository) GetTransaction() (*sql.Tx, error) {
 	tx, err := r.connection.Begin()
 	if err != nil {
@@ -0,0 +1,93 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+)
+
+// InsertImageVariant records a resized copy of a page image in the variante_imagem_tabloide table.
+// Variants belong to the stored image rather than to a page row, so pages pointing to the same image
+// (for example after restoring a version) share them.
+// It returns an error if the operation fails.
+//
+// Example:
+//
+//	variant := interfaces.PageVariant{Width: 200, Key: "RPA/v3/42/campanha-42-...-pagina-1-w200.png"}
+//	err := repository.InsertImageVariant("RPA/v3/42/campanha-42-...-pagina-1.png", variant, transaction)
+//	if err != nil {
+//	    log.Fatalf("Failed to insert variant: %v", err)
+//	}
+func (r *MysqlTabloideRepository) InsertImageVariant(imageKey string, variant interfaces.PageVariant, transaction *sql.Tx) error {
+	query :=
+		`INSERT INTO variante_imagem_tabloide
+		(imagem_key, largura, variante_key, dt_cadastro)
+		VALUES (?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE variante_key = VALUES(variante_key)`
+
+	_, err := transaction.Exec(query, imageKey, variant.Width, variant.Key)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// attachVariants fills the Variants of each page with the resized copies of its image.
+// Pages are matched by image key, or by image URL for rows not converted by the backfill yet.
+func (r *MysqlTabloideRepository) attachVariants(pages []interfaces.Page) error {
+	if len(pages) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(pages)), ", ")
+	args := make([]interface{}, 0, 2*len(pages))
+	for _, page := range pages {
+		args = append(args, pageImageIdentity(page))
+	}
+	args = append(args, args...)
+
+	query := `SELECT imagem_key, imagem_url, largura, variante_key, variante_url FROM variante_imagem_tabloide
+		WHERE imagem_key IN (` + placeholders + `) OR imagem_url IN (` + placeholders + `) ORDER BY largura`
+
+	rows, err := r.connection.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	variantsByImage := map[string][]interfaces.PageVariant{}
+	for rows.Next() {
+		var imageKey, imageURL, variantKey, variantURL sql.NullString
+		var variant interfaces.PageVariant
+		if err := rows.Scan(&imageKey, &imageURL, &variant.Width, &variantKey, &variantURL); err != nil {
+			return fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		identity := imageKey.String
+		if !imageKey.Valid {
+			identity = imageURL.String
+		}
+		variant.Key = variantKey.String
+		if !variantKey.Valid {
+			variant.ImageURL = variantURL.String
+		}
+		variantsByImage[identity] = append(variantsByImage[identity], variant)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	for i := range pages {
+		pages[i].Variants = variantsByImage[pageImageIdentity(pages[i])]
+	}
+	return nil
+}
+
+// pageImageIdentity returns the key of the page image, or its URL for rows not converted by the backfill yet.
+func pageImageIdentity(page interfaces.Page) string {
+	if page.ImageKey != "" {
+		return page.ImageKey
+	}
+	return page.ImageURL
+}
@@ -0,0 +1,146 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"test/lambda/interfaces"
+)
+
+// InsertTabloidVersion stores a new immutable version of a tabloid in the versao_tabloide table.
+// The version number is the next sequential number of the tabloid; it is computed while holding a lock on
+// the tabloid versions, so concurrent transactions cannot create the same version twice.
+// It returns the number assigned to the new version or an error if the operation fails.
+//
+// Example:
+//
+//	version := interfaces.TabloidVersion{
+//	    TabloidID:        42,
+//	    Nome:             tabloid.Nome,
+//	    RegiaoID:         tabloid.RegiaoID,
+//	    DtInicioVigencia: tabloid.DtInicioVigencia,
+//	    DtFimVigencia:    tabloid.DtFimVigencia,
+//	    Ativo:            tabloid.Ativo,
+//	    Pages:            []string{"RPA/v3/42/campanha-42-pagina-1.png"},
+//	    Actor:            "marcos",
+//	}
+//	number, err := repository.InsertTabloidVersion(version, transaction)
+//	if err != nil {
+//	    log.Fatalf("Failed to insert version: %v", err)
+//	}
+//	fmt.Println("Created version", number)
+func (r *MysqlTabloideRepository) InsertTabloidVersion(version interfaces.TabloidVersion, transaction *sql.Tx) (int, error) {
+	var lastVersion int
+	err := transaction.QueryRow(
+		`SELECT COALESCE(MAX(versao), 0) FROM versao_tabloide WHERE tabloide_id = ? FOR UPDATE`, version.TabloidID,
+	).Scan(&lastVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+
+	pages, err := json.Marshal(version.Pages)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal pages: %v", err)
+	}
+
+	query :=
+		`INSERT INTO versao_tabloide
+		(tabloide_id, versao, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo, paginas, restaurado_de, usuario, dt_cadastro)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`
+
+	_, err = transaction.Exec(query, version.TabloidID, lastVersion+1, version.Nome, version.RegiaoID,
+		version.DtInicioVigencia, version.DtFimVigencia, version.Ativo, string(pages), version.RestoredFrom, version.Actor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+
+	return lastVersion + 1, nil
+}
+
+// GetTabloidVersions retrieves every version of a tabloid, oldest first.
+// It returns an empty slice if the tabloid has no versions.
+//
+// Example:
+//
+//	versions, err := repository.GetTabloidVersions(42)
+//	if err != nil {
+//	    log.Fatalf("Failed to retrieve versions: %v", err)
+//	}
+//	for _, version := range versions {
+//	    fmt.Println(version.Version, version.Actor, len(version.Pages))
+//	}
+func (r *MysqlTabloideRepository) GetTabloidVersions(tabloidID int64) ([]interfaces.TabloidVersion, error) {
+	query := versionColumns + ` WHERE tabloide_id = ? ORDER BY versao`
+
+	rows, err := r.connection.Query(query, tabloidID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	versions := []interfaces.TabloidVersion{}
+	for rows.Next() {
+		version, err := scanTabloidVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	return versions, nil
+}
+
+// GetTabloidVersion retrieves one version of a tabloid by its number.
+// It returns an error wrapping sql.ErrNoRows if the version does not exist.
+//
+// Example:
+//
+//	version, err := repository.GetTabloidVersion(42, 3)
+//	if errors.Is(err, sql.ErrNoRows) {
+//	    fmt.Println("Version not found")
+//	}
+func (r *MysqlTabloideRepository) GetTabloidVersion(tabloidID int64, number int) (*interfaces.TabloidVersion, error) {
+	query := versionColumns + ` WHERE tabloide_id = ? AND versao = ? LIMIT 1`
+	return scanTabloidVersion(r.connection.QueryRow(query, tabloidID, number))
+}
+
+// versionColumns selects the columns read by scanTabloidVersion.
+const versionColumns = `SELECT id, tabloide_id, versao, nome, regiao_id, dt_inicio_vigencia, dt_fim_vigencia, ativo,
+	paginas, restaurado_de, usuario, dt_cadastro FROM versao_tabloide`
+
+// scanTabloidVersion scans a versao_tabloide row selected with versionColumns.
+func scanTabloidVersion(row interface{ Scan(dest ...any) error }) (*interfaces.TabloidVersion, error) {
+	var version interfaces.TabloidVersion
+	var dtInicioVigencia, dtFimVigencia, dtCadastro []uint8
+	var pages string
+	var restoredFrom sql.NullInt64
+
+	err := row.Scan(&version.ID, &version.TabloidID, &version.Version, &version.Nome, &version.RegiaoID,
+		&dtInicioVigencia, &dtFimVigencia, &version.Ativo, &pages, &restoredFrom, &version.Actor, &dtCadastro)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(pages), &version.Pages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal paginas: %v", err)
+	}
+	if restoredFrom.Valid {
+		restored := int(restoredFrom.Int64)
+		version.RestoredFrom = &restored
+	}
+
+	if version.DtInicioVigencia, err = parseDateTime(dtInicioVigencia); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_inicio_vigencia: %v", err)
+	}
+	if version.DtFimVigencia, err = parseDateTime(dtFimVigencia); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_fim_vigencia: %v", err)
+	}
+	if version.CreatedAt, err = parseDateTime(dtCadastro); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+	}
+
+	return &version, nil
+}
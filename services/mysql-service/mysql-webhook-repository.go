@@ -0,0 +1,283 @@
+package mysqlservice
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// webhookDeliveryColumns are the columns of webhook_entrega read by scanWebhookDelivery, aliased "e", along with the
+// type and the deduplication ID of their outbox event, aliased "o".
+const webhookDeliveryColumns = `e.id, e.assinatura_id, e.evento_id, o.tipo, o.id_deduplicacao, e.status, e.tentativas,
+	e.ultimo_status_http, e.ultimo_erro, e.dt_proxima_tentativa, e.dt_entrega, e.dt_cadastro`
+
+// InsertWebhookSubscription writes a webhook subscription to the webhook_assinatura table.
+// It returns the ID of the subscription.
+func (r *MysqlTabloideRepository) InsertWebhookSubscription(subscription interfaces.WebhookSubscription) (int64, error) {
+	eventTypes, err := json.Marshal(subscription.EventTypes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event types: %v", err)
+	}
+	result, err := r.connection.Exec(
+		`INSERT INTO webhook_assinatura (url, segredo, regiao_id, tipos, ativo, usuario, dt_cadastro)
+		VALUES (?, ?, ?, ?, 1, ?, NOW())`,
+		subscription.URL, subscription.Secret, subscription.RegionID, string(eventTypes), subscription.Actor,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetWebhookSubscriptions retrieves the active webhook subscriptions, without their secrets.
+func (r *MysqlTabloideRepository) GetWebhookSubscriptions() ([]interfaces.WebhookSubscription, error) {
+	rows, err := r.connection.Query(
+		`SELECT id, url, regiao_id, tipos, ativo, usuario, dt_cadastro FROM webhook_assinatura WHERE ativo = 1 ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []interfaces.WebhookSubscription{}
+	for rows.Next() {
+		var subscription interfaces.WebhookSubscription
+		var regionID sql.NullInt64
+		var eventTypes string
+		var dtCadastro []uint8
+		err := rows.Scan(&subscription.ID, &subscription.URL, &regionID, &eventTypes, &subscription.Active,
+			&subscription.Actor, &dtCadastro)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if regionID.Valid {
+			region := int(regionID.Int64)
+			subscription.RegionID = &region
+		}
+		if err := json.Unmarshal([]byte(eventTypes), &subscription.EventTypes); err != nil {
+			return nil, fmt.Errorf("failed to parse tipos: %v", err)
+		}
+		if subscription.CreatedAt, err = parseDateTime(dtCadastro); err != nil {
+			return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+	return subscriptions, nil
+}
+
+// DeactivateWebhookSubscription stops the delivery of events to a subscription. Its pending deliveries are dropped.
+// It returns sql.ErrNoRows if there is no active subscription with the ID.
+func (r *MysqlTabloideRepository) DeactivateWebhookSubscription(subscriptionID int64) error {
+	result, err := r.connection.Exec(`UPDATE webhook_assinatura SET ativo = 0 WHERE id = ? AND ativo = 1`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("subscription %d: %w", subscriptionID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// InsertWebhookDeliveries creates a pending delivery of an outbox event to every active subscription that matches its
+// region and type, due now. Subscriptions without region or types match every event.
+// Deliveries already created for the event are kept, so fanning out the same event twice is harmless.
+func (r *MysqlTabloideRepository) InsertWebhookDeliveries(event interfaces.DomainEvent, transaction *sql.Tx) (int64, error) {
+	result, err := transaction.Exec(
+		`INSERT IGNORE INTO webhook_entrega (assinatura_id, evento_id, status, tentativas, dt_proxima_tentativa, dt_cadastro)
+		SELECT id, ?, ?, 0, NOW(), NOW() FROM webhook_assinatura
+		WHERE ativo = 1 AND (regiao_id IS NULL OR regiao_id = ?)
+		AND (JSON_LENGTH(tipos) = 0 OR JSON_CONTAINS(tipos, JSON_QUOTE(?)))`,
+		event.ID, interfaces.WebhookDeliveryPending, event.RegionID, event.Type,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClaimDueWebhookDeliveries claims up to limit deliveries of active subscriptions whose next attempt is due, oldest
+// first: they are marked as sending for lease, so the transaction can be committed before they are sent. Deliveries
+// whose lease expired, as their dispatcher stopped before recording the result, are claimed again. Rows locked by
+// another dispatcher claiming at the same time are skipped.
+func (r *MysqlTabloideRepository) ClaimDueWebhookDeliveries(limit int, lease time.Duration, transaction *sql.Tx) ([]interfaces.DueWebhook, error) {
+	due, err := r.getDueWebhookDeliveries(limit, transaction)
+	if err != nil || len(due) == 0 {
+		return due, err
+	}
+
+	args := []any{interfaces.WebhookDeliverySending, int64(lease.Seconds())}
+	for _, webhook := range due {
+		args = append(args, webhook.Delivery.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(due)), ", ")
+	_, err = transaction.Exec(
+		`UPDATE webhook_entrega SET status = ?, dt_proxima_tentativa = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE id IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	return due, nil
+}
+
+// getDueWebhookDeliveries locks up to limit due deliveries, pending or with an expired lease, skipping those locked
+// by another dispatcher. The locks are held until the transaction ends.
+func (r *MysqlTabloideRepository) getDueWebhookDeliveries(limit int, transaction *sql.Tx) ([]interfaces.DueWebhook, error) {
+	rows, err := transaction.Query(
+		`SELECT `+webhookDeliveryColumns+`, a.url, a.segredo, o.tabloide_id, o.regiao_id, o.payload, o.dt_cadastro
+		FROM webhook_entrega e
+		JOIN webhook_assinatura a ON a.id = e.assinatura_id
+		JOIN outbox_evento o ON o.id = e.evento_id
+		WHERE e.status IN (?, ?) AND e.dt_proxima_tentativa <= NOW() AND a.ativo = 1
+		ORDER BY e.dt_proxima_tentativa, e.id LIMIT ? FOR UPDATE OF e SKIP LOCKED`,
+		interfaces.WebhookDeliveryPending, interfaces.WebhookDeliverySending, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	due := []interfaces.DueWebhook{}
+	for rows.Next() {
+		var webhook interfaces.DueWebhook
+		var payload string
+		var occurredAt []uint8
+		delivery, err := scanWebhookDelivery(rows, &webhook.URL, &webhook.Secret, &webhook.Event.TabloidID,
+			&webhook.Event.RegionID, &payload, &occurredAt)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Delivery = *delivery
+		webhook.Event.ID = delivery.EventID
+		webhook.Event.Type = delivery.EventType
+		webhook.Event.DeduplicationID = delivery.DeduplicationID
+		webhook.Event.Payload = []byte(payload)
+		if webhook.Event.OccurredAt, err = parseDateTime(occurredAt); err != nil {
+			return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+		}
+		due = append(due, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+	return due, nil
+}
+
+// MarkWebhookDelivered records that a delivery was answered with statusCode, a 2xx.
+func (r *MysqlTabloideRepository) MarkWebhookDelivered(deliveryID int64, statusCode int, transaction *sql.Tx) error {
+	_, err := transaction.Exec(
+		`UPDATE webhook_entrega SET status = ?, ultimo_status_http = ?, ultimo_erro = NULL, dt_entrega = NOW() WHERE id = ?`,
+		interfaces.WebhookDeliveryDelivered, statusCode, deliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// MarkWebhookFailed records a failed attempt of a delivery: its status code (0 if there was no answer) and error.
+// The next attempt is due after retryIn; a dead delivery is not attempted again until it is replayed.
+func (r *MysqlTabloideRepository) MarkWebhookFailed(deliveryID int64, statusCode int, deliveryError string, dead bool,
+	retryIn time.Duration, transaction *sql.Tx) error {
+	status := interfaces.WebhookDeliveryPending
+	if dead {
+		status = interfaces.WebhookDeliveryDead
+	}
+	var lastStatus sql.NullInt64
+	if statusCode != 0 {
+		lastStatus = sql.NullInt64{Int64: int64(statusCode), Valid: true}
+	}
+	_, err := transaction.Exec(
+		`UPDATE webhook_entrega SET status = ?, tentativas = tentativas + 1, ultimo_status_http = ?, ultimo_erro = ?,
+		dt_proxima_tentativa = DATE_ADD(NOW(), INTERVAL ? SECOND) WHERE id = ?`,
+		status, lastStatus, deliveryError, int64(retryIn.Seconds()), deliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries retrieves the deliveries of a subscription, newest first, up to limit.
+func (r *MysqlTabloideRepository) GetWebhookDeliveries(subscriptionID int64, limit int) ([]interfaces.WebhookDelivery, error) {
+	rows, err := r.connection.Query(
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_entrega e JOIN outbox_evento o ON o.id = e.evento_id
+		WHERE e.assinatura_id = ? ORDER BY e.id DESC LIMIT ?`,
+		subscriptionID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []interfaces.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery makes a delivery pending again and due now, with its attempts reset, whatever its status.
+// It returns sql.ErrNoRows if there is no such delivery.
+func (r *MysqlTabloideRepository) ReplayWebhookDelivery(deliveryID int64) error {
+	result, err := r.connection.Exec(
+		`UPDATE webhook_entrega SET status = ?, tentativas = 0, dt_proxima_tentativa = NOW(), dt_entrega = NULL WHERE id = ?`,
+		interfaces.WebhookDeliveryPending, deliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("delivery %d: %w", deliveryID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// scanWebhookDelivery reads a row starting with webhookDeliveryColumns; the remaining columns are read into extra.
+func scanWebhookDelivery(rows *sql.Rows, extra ...any) (*interfaces.WebhookDelivery, error) {
+	var delivery interfaces.WebhookDelivery
+	var lastStatus sql.NullInt64
+	var lastError sql.NullString
+	var dtProximaTentativa, dtEntrega, dtCadastro []uint8
+	dest := append([]any{&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType,
+		&delivery.DeduplicationID, &delivery.Status, &delivery.Attempts, &lastStatus, &lastError,
+		&dtProximaTentativa, &dtEntrega, &dtCadastro}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %v", err)
+	}
+
+	if lastStatus.Valid {
+		statusCode := int(lastStatus.Int64)
+		delivery.LastStatusCode = &statusCode
+	}
+	delivery.LastError = lastError.String
+	var err error
+	if delivery.NextAttemptAt, err = parseDateTime(dtProximaTentativa); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_proxima_tentativa: %v", err)
+	}
+	if dtEntrega != nil {
+		deliveredAt, err := parseDateTime(dtEntrega)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dt_entrega: %v", err)
+		}
+		delivery.DeliveredAt = &deliveredAt
+	}
+	if delivery.CreatedAt, err = parseDateTime(dtCadastro); err != nil {
+		return nil, fmt.Errorf("failed to parse dt_cadastro: %v", err)
+	}
+	return &delivery, nil
+}
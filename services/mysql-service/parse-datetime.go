@@ -0,0 +1,16 @@
+package mysqlservice
+
+import "time"
+
+// parseDateTime parses a DATE or DATETIME column scanned as raw bytes.
+// The connection is opened without parseTime, so the driver returns the textual representation.
+// It returns the zero time for NULL columns.
+func parseDateTime(value []uint8) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	if len(value) == len("2006-01-02") {
+		return time.Parse("2006-01-02", string(value))
+	}
+	return time.Parse("2006-01-02 15:04:05", string(value))
+}
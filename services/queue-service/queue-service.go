@@ -0,0 +1,90 @@
+// Package queueservice provides the queues that hand work from the HTTP API to the asynchronous consumers.
+package queueservice
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Queue delivers message bodies to a consumer, at least once.
+type Queue interface {
+	Send(ctx context.Context, body []byte) error
+}
+
+// SQSQueue sends messages to an SQS queue, consumed by a Lambda function with an SQS event source.
+type SQSQueue struct {
+	Client   *sqs.Client
+	QueueURL string
+}
+
+// NewSQSQueue creates an SQSQueue sending to the queue at queueURL.
+// It returns an error if queueURL is empty or the AWS configuration is invalid.
+func NewSQSQueue(queueURL string) (*SQSQueue, error) {
+	if queueURL == "" {
+		return nil, fmt.Errorf("queue URL is not configured")
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(os.Getenv("REGION")))
+	if err != nil {
+		return nil, err
+	}
+	return &SQSQueue{Client: sqs.NewFromConfig(cfg), QueueURL: queueURL}, nil
+}
+
+// Send sends one message to the queue.
+func (q *SQSQueue) Send(ctx context.Context, body []byte) error {
+	_, err := q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.QueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to %s: %w", q.QueueURL, err)
+	}
+	return nil
+}
+
+// LocalQueue is an in-process queue for running locally without AWS: messages are consumed by goroutines of the
+// same process. Like SQS, a message whose consumer fails is delivered again, up to maxAttempts times in all.
+// Messages still queued when the process exits are lost.
+type LocalQueue struct {
+	messages    chan []byte
+	consumer    func(ctx context.Context, body []byte) error
+	maxAttempts int
+}
+
+// NewLocalQueue creates a LocalQueue delivering messages to consumer from the given number of goroutines.
+func NewLocalQueue(consumer func(ctx context.Context, body []byte) error, workers int) *LocalQueue {
+	q := &LocalQueue{messages: make(chan []byte, 100), consumer: consumer, maxAttempts: 3}
+	for i := 0; i < max(workers, 1); i++ {
+		go q.consume()
+	}
+	return q
+}
+
+// Send queues one message, waiting while the queue is full.
+func (q *LocalQueue) Send(ctx context.Context, body []byte) error {
+	select {
+	case q.messages <- body:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// consume delivers the queued messages to the consumer until the process exits.
+func (q *LocalQueue) consume() {
+	for body := range q.messages {
+		for attempt := 1; attempt <= q.maxAttempts; attempt++ {
+			err := q.consumer(context.Background(), body)
+			if err == nil {
+				break
+			}
+			fmt.Printf("local queue: attempt %d of %d failed: %v\n", attempt, q.maxAttempts, err)
+		}
+	}
+}
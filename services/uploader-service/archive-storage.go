@@ -0,0 +1,125 @@
+package uploaderservice
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// archiveStorageClasses are the storage classes pages can be archived in. Only classes readable without
+// a restore request are accepted, so archived tabloids can be brought back at once.
+var archiveStorageClasses = map[types.StorageClass]bool{
+	types.StorageClassStandardIa:         true,
+	types.StorageClassOnezoneIa:          true,
+	types.StorageClassIntelligentTiering: true,
+	types.StorageClassGlacierIr:          true,
+}
+
+// ArchiveLocation is where archived page images are kept.
+type ArchiveLocation struct {
+	Bucket       string             // Archive bucket, ARCHIVE_BUCKET (the page bucket by default).
+	Prefix       string             // Prefix of archived keys, ARCHIVE_PREFIX ("archive/" by default).
+	StorageClass types.StorageClass // Storage class of archived objects, ARCHIVE_STORAGE_CLASS (GLACIER_IR by default).
+}
+
+// ArchiveLocationFromEnv reads the archive location from the environment.
+// It returns an error if the storage class cannot be read without a restore request.
+func ArchiveLocationFromEnv() (*ArchiveLocation, error) {
+	location := &ArchiveLocation{
+		Bucket:       os.Getenv("ARCHIVE_BUCKET"),
+		Prefix:       os.Getenv("ARCHIVE_PREFIX"),
+		StorageClass: types.StorageClass(os.Getenv("ARCHIVE_STORAGE_CLASS")),
+	}
+	if location.Bucket == "" {
+		location.Bucket = os.Getenv("AWS_S3_BUCKET_NAME_S3")
+	}
+	if location.Prefix == "" {
+		location.Prefix = "archive/"
+	}
+	if location.StorageClass == "" {
+		location.StorageClass = types.StorageClassGlacierIr
+	}
+	if !archiveStorageClasses[location.StorageClass] {
+		return nil, fmt.Errorf("invalid ARCHIVE_STORAGE_CLASS: %s", location.StorageClass)
+	}
+	return location, nil
+}
+
+// ArchiveImage copies the image stored under key, the given resized copies and its original to the archive,
+// under the same keys after the archive prefix. The original is archived in its private bucket. The page objects
+// are kept; they are deleted with DeleteImage once the pages point to the archive.
+func (adapter *UploaderAdapter) ArchiveImage(location *ArchiveLocation, key string, variantKeys []string) error {
+	keys, err := adapter.imageObjectKeys(key, variantKeys)
+	if err != nil {
+		return err
+	}
+
+	for _, objectKey := range keys {
+		if err := adapter.copyObjectBetween(objectBucket(objectKey), objectKey, location.archiveBucket(objectKey),
+			location.Prefix+objectKey, location.StorageClass); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreArchivedImage copies the image stored under key, the given resized copies and its original back from
+// the archive to their buckets. The archived objects are kept for the retention period.
+func (adapter *UploaderAdapter) RestoreArchivedImage(location *ArchiveLocation, key string, variantKeys []string) error {
+	keys := append([]string{key}, variantKeys...)
+
+	// Originals are listed in the archive, as their bucket no longer has them
+	if prefix := os.Getenv("IMAGE_ORIGINALS_PREFIX"); prefix != "" {
+		original := originalKey(prefix, key, "")
+		archived, err := adapter.listKeys(location.archiveBucket(original), location.Prefix+original)
+		if err != nil {
+			return err
+		}
+		for _, archivedKey := range archived {
+			keys = append(keys, archivedKey[len(location.Prefix):])
+		}
+	}
+
+	for _, objectKey := range keys {
+		if err := adapter.copyObjectBetween(location.archiveBucket(objectKey), location.Prefix+objectKey, objectBucket(objectKey),
+			objectKey, types.StorageClassStandard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveBucket returns the bucket the object stored under key is archived in: the archive bucket, except for
+// originals, which stay in their private bucket.
+func (location *ArchiveLocation) archiveBucket(key string) string {
+	if isOriginalKey(key) {
+		return objectBucket(key)
+	}
+	return location.Bucket
+}
+
+// DeleteImage deletes the image stored under key from the page bucket, along with the given resized copies
+// and, from its bucket, its original.
+func (adapter *UploaderAdapter) DeleteImage(key string, variantKeys []string) error {
+	keys, err := adapter.imageObjectKeys(key, variantKeys)
+	if err != nil {
+		return err
+	}
+	for _, objectKey := range keys {
+		if err := adapter.DeleteObject(objectKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageObjectKeys returns the keys of every object stored for an image: the image, its resized copies and its originals.
+func (adapter *UploaderAdapter) imageObjectKeys(key string, variantKeys []string) ([]string, error) {
+	originals, err := adapter.originalKeys(key)
+	if err != nil {
+		return nil, err
+	}
+	keys := append([]string{key}, variantKeys...)
+	return append(keys, originals...), nil
+}
@@ -0,0 +1,148 @@
+package uploaderservice
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"test/lambda/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ImageVariant represents a resized copy of an uploaded image stored in the S3 bucket.
+type ImageVariant struct {
+	Width int    // Width of the copy in pixels.
+	Key   string // Key under which the copy is stored.
+}
+
+// UploadImageVariants stores resized copies of an image of the given page next to the original stored under key,
+// with the same metadata, tags and encryption.
+// One copy is stored for each width narrower than the image; wider widths are skipped, as images are never upscaled.
+// It returns the stored copies, narrowest first, or an error if resizing or uploading fails.
+//
+// Example:
+//
+//	variants, err := adapter.UploadImageVariants(ctx, image, key, ref, []int{200, 600, 1200})
+//	if err != nil {
+//	    log.Fatalf("Failed to upload variants: %v", err)
+//	}
+//	for _, variant := range variants {
+//	    fmt.Println(variant.Width, variant.Key) // 200 RPA/v3/42/campanha-42-...-pagina-1-w200.png
+//	}
+func (adapter *UploaderAdapter) UploadImageVariants(ctx context.Context, image []byte, key string, ref PageRef, widths []int) ([]ImageVariant, error) {
+	var variants []ImageVariant
+	for _, width := range widths {
+		if err := ctx.Err(); err != nil {
+			return variants, err
+		}
+		resized, ok, err := utils.ResizeImage(image, width)
+		if err != nil {
+			return variants, err
+		}
+		if !ok {
+			continue
+		}
+
+		variant := ImageVariant{Width: width, Key: variantKey(key, width, adapter.getImageExtension(resized))}
+		if _, err := adapter.storeObject(ctx, variant.Key, resized, ref); err != nil {
+			return variants, err
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// variantKey derives the key of a resized copy from the key of the original image, adding the width
+// and using the extension of the copy: "pagina-1.webp" becomes "pagina-1-w200.png".
+func variantKey(key string, width int, extension string) string {
+	return fmt.Sprintf("%s-w%d%s", strings.TrimSuffix(key, path.Ext(key)), width, extension)
+}
+
+// CopyImage copies the image stored under key to newKey, along with its resized copies and, when
+// IMAGE_ORIGINALS_PREFIX is set, its untouched original, copied within the originals bucket. The objects under the
+// old keys are kept.
+// It returns the resized copies under their new keys.
+func (adapter *UploaderAdapter) CopyImage(key, newKey string, variants []ImageVariant) ([]ImageVariant, error) {
+	if err := adapter.copyObject(key, newKey); err != nil {
+		return nil, err
+	}
+
+	copied := make([]ImageVariant, 0, len(variants))
+	for _, variant := range variants {
+		newVariant := ImageVariant{Width: variant.Width, Key: variantKey(newKey, variant.Width, path.Ext(variant.Key))}
+		if err := adapter.copyObject(variant.Key, newVariant.Key); err != nil {
+			return nil, err
+		}
+		copied = append(copied, newVariant)
+	}
+
+	prefix := os.Getenv("IMAGE_ORIGINALS_PREFIX")
+	originals, err := adapter.originalKeys(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, original := range originals {
+		if err := adapter.copyObject(original, originalKey(prefix, newKey, path.Ext(original))); err != nil {
+			return nil, err
+		}
+	}
+
+	return copied, nil
+}
+
+// originalKeys returns the keys of the untouched originals kept for the image stored under key, when
+// IMAGE_ORIGINALS_PREFIX is set. Originals keep the extension of the upload, which may differ from the stored image.
+func (adapter *UploaderAdapter) originalKeys(key string) ([]string, error) {
+	prefix := os.Getenv("IMAGE_ORIGINALS_PREFIX")
+	if prefix == "" {
+		return nil, nil
+	}
+
+	listed, err := adapter.S3Client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(os.Getenv("IMAGE_ORIGINALS_BUCKET")),
+		Prefix: aws.String(originalKey(prefix, key, "")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list originals: %w", err)
+	}
+
+	var originals []string
+	for _, object := range listed.Contents {
+		original := aws.ToString(object.Key)
+		if original == originalKey(prefix, key, path.Ext(original)) {
+			originals = append(originals, original)
+		}
+	}
+	return originals, nil
+}
+
+// copyObject copies an object to another key, each in its bucket (see objectBucket), keeping its metadata and tags.
+// The copy is encrypted with IMAGE_KMS_KEY_ID, when set.
+func (adapter *UploaderAdapter) copyObject(key, newKey string) error {
+	return adapter.copyObjectBetween(objectBucket(key), key, objectBucket(newKey), newKey, "")
+}
+
+// copyObjectBetween copies an object to another bucket and key, keeping its metadata and tags, in the given
+// storage class (the default of the bucket when empty). The copy is encrypted with IMAGE_KMS_KEY_ID, when set.
+func (adapter *UploaderAdapter) copyObjectBetween(bucket, key, newBucket, newKey string, storageClass types.StorageClass) error {
+	input := &s3.CopyObjectInput{
+		Bucket:       aws.String(newBucket),
+		CopySource:   aws.String(url.PathEscape(bucket + "/" + key)),
+		Key:          aws.String(newKey),
+		StorageClass: storageClass,
+	}
+	if keyID := kmsKeyID(); keyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+	_, err := adapter.S3Client.CopyObject(context.Background(), input)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s/%s to %s/%s: %w", bucket, key, newBucket, newKey, err)
+	}
+	return nil
+}
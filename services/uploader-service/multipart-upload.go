@@ -0,0 +1,96 @@
+package uploaderservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"test/lambda/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// minMultipartPartSize is the smallest part S3 accepts, except for the last one.
+const minMultipartPartSize = 5 << 20
+
+// multipartThreshold returns the size from which objects are sent with a multipart upload, from
+// IMAGE_MULTIPART_THRESHOLD (8 MiB by default, below the 10 MiB of UPLOAD_MAX_PAGE_BYTES, so the largest pages use it).
+func multipartThreshold() int64 {
+	return int64(utils.GetEnvInt("IMAGE_MULTIPART_THRESHOLD", 8<<20))
+}
+
+// multipartPartSize returns the size of the parts of multipart uploads, from IMAGE_MULTIPART_PART_SIZE
+// (8 MiB by default, 5 MiB at least).
+func multipartPartSize() int {
+	return max(utils.GetEnvInt("IMAGE_MULTIPART_PART_SIZE", 8<<20), minMultipartPartSize)
+}
+
+// putObjectMultipart stores content with a multipart upload, with the same settings as input.
+// Each part carries its SHA-256 checksum. The upload is aborted if any part fails, so no incomplete parts are billed.
+func (adapter *UploaderAdapter) putObjectMultipart(ctx context.Context, input *s3.PutObjectInput, content []byte) error {
+	upload, err := adapter.S3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               input.Bucket,
+		Key:                  input.Key,
+		ContentType:          input.ContentType,
+		CacheControl:         input.CacheControl,
+		Metadata:             input.Metadata,
+		Tagging:              input.Tagging,
+		ChecksumAlgorithm:    types.ChecksumAlgorithmSha256,
+		ServerSideEncryption: input.ServerSideEncryption,
+		SSEKMSKeyId:          input.SSEKMSKeyId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	parts, err := adapter.uploadParts(ctx, input, upload.UploadId, content)
+	if err == nil {
+		_, err = adapter.S3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          input.Bucket,
+			Key:             input.Key,
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+	}
+	if err != nil {
+		// Abort even when ctx was cancelled, so the parts are not left behind
+		if _, abortErr := adapter.S3Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   input.Bucket,
+			Key:      input.Key,
+			UploadId: upload.UploadId,
+		}); abortErr != nil {
+			fmt.Println("failed to abort multipart upload:", abortErr)
+		}
+		return fmt.Errorf("failed to upload %s in parts: %w", aws.ToString(input.Key), err)
+	}
+	return nil
+}
+
+// uploadParts sends content as the parts of a multipart upload and returns them, in order.
+func (adapter *UploaderAdapter) uploadParts(ctx context.Context, input *s3.PutObjectInput, uploadID *string, content []byte) ([]types.CompletedPart, error) {
+	partSize := multipartPartSize()
+	var parts []types.CompletedPart
+	for offset, number := 0, int32(1); offset < len(content); offset, number = offset+partSize, number+1 {
+		part := content[offset:min(offset+partSize, len(content))]
+		checksum := sha256.Sum256(part)
+		encoded := aws.String(base64.StdEncoding.EncodeToString(checksum[:]))
+
+		uploaded, err := adapter.S3Client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            input.Bucket,
+			Key:               input.Key,
+			UploadId:          uploadID,
+			PartNumber:        aws.Int32(number),
+			Body:              bytes.NewReader(part),
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+			ChecksumSHA256:    encoded,
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, types.CompletedPart{ETag: uploaded.ETag, PartNumber: aws.Int32(number), ChecksumSHA256: encoded})
+	}
+	return parts, nil
+}
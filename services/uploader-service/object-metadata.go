@@ -0,0 +1,235 @@
+package uploaderservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"test/lambda/interfaces"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Names of the user metadata and tags attached to every stored object.
+const (
+	ObjectTagTabloidID   = "tabloide-id"
+	ObjectTagRegionID    = "regiao-id"
+	ObjectTagPage        = "ordem"
+	ObjectTagUploader    = "usuario"
+	ObjectTagValidityEnd = "fim-vigencia"
+)
+
+// defaultCacheControl is the Cache-Control of stored objects. Keys are never reused for other content,
+// so objects can be cached for good.
+const defaultCacheControl = "public, max-age=31536000, immutable"
+
+// PageRef identifies the page an uploaded image belongs to, and who uploaded it.
+type PageRef struct {
+	Tabloid  interfaces.Tabloid // Tabloid the page belongs to.
+	Order    int                // Zero-based position of the page in the tabloid.
+	Uploader string             // Username of whoever uploaded the page.
+}
+
+// objectTags returns the tags (and user metadata) describing the page. fim-vigencia lets bucket
+// lifecycle rules expire or tier the objects of old tabloids.
+func (ref PageRef) objectTags() map[string]string {
+	return map[string]string{
+		ObjectTagTabloidID:   strconv.FormatInt(ref.Tabloid.ID, 10),
+		ObjectTagRegionID:    strconv.Itoa(ref.Tabloid.RegiaoID),
+		ObjectTagPage:        strconv.Itoa(ref.Order),
+		ObjectTagUploader:    ref.Uploader,
+		ObjectTagValidityEnd: ref.Tabloid.DtFimVigencia.Format("2006-01-02"),
+	}
+}
+
+// objectMetadata returns the user metadata stored with the objects of the page.
+func (ref PageRef) objectMetadata() map[string]string {
+	metadata := ref.objectTags()
+	for key, value := range metadata {
+		metadata[key] = utils.SanitizeTagValue(value)
+	}
+	return metadata
+}
+
+// objectCacheControl returns the Cache-Control header of stored objects, from IMAGE_CACHE_CONTROL.
+func objectCacheControl() string {
+	if cacheControl := os.Getenv("IMAGE_CACHE_CONTROL"); cacheControl != "" {
+		return cacheControl
+	}
+	return defaultCacheControl
+}
+
+// kmsKeyID returns the KMS key objects are encrypted with, from IMAGE_KMS_KEY_ID.
+// Objects use the default encryption of the bucket when it is empty.
+func kmsKeyID() string {
+	return os.Getenv("IMAGE_KMS_KEY_ID")
+}
+
+// extendValidityTag moves the fim-vigencia tag of a shared object forward when the page reusing it is valid
+// for longer, so lifecycle rules do not expire an object another tabloid still shows.
+func (adapter *UploaderAdapter) extendValidityTag(ctx context.Context, key string, ref PageRef) error {
+	bucket := objectBucket(key)
+	current, err := adapter.S3Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get tags of %s: %w", key, err)
+	}
+
+	validityEnd := ref.objectTags()[ObjectTagValidityEnd]
+	tags := make([]types.Tag, 0, len(current.TagSet))
+	for _, tag := range current.TagSet {
+		if aws.ToString(tag.Key) == ObjectTagValidityEnd {
+			// Dates are formatted as YYYY-MM-DD, so they compare as strings
+			if aws.ToString(tag.Value) >= validityEnd {
+				return nil
+			}
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	tags = append(tags, types.Tag{Key: aws.String(ObjectTagValidityEnd), Value: aws.String(validityEnd)})
+
+	_, err = adapter.S3Client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tags},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag %s: %w", key, err)
+	}
+	return nil
+}
+
+// refreshObject copies a stored object onto itself, keeping its content, metadata and tags, so its LastModified
+// becomes now: the reconciliation only treats objects unreferenced for longer than its grace period as orphans.
+// It returns false, without error, if the object does not exist.
+func (adapter *UploaderAdapter) refreshObject(ctx context.Context, key string) (bool, error) {
+	bucket := objectBucket(key)
+	head, err := adapter.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to head %s: %w", key, err)
+	}
+
+	// S3 only copies an object onto itself when something changes, so the metadata is replaced by itself
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String(url.PathEscape(bucket + "/" + key)),
+		MetadataDirective: types.MetadataDirectiveReplace,
+		Metadata:          head.Metadata,
+		ContentType:       head.ContentType,
+		CacheControl:      head.CacheControl,
+	}
+	if keyID := kmsKeyID(); keyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+	if _, err := adapter.S3Client.CopyObject(ctx, input); err != nil {
+		return false, fmt.Errorf("failed to refresh %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// ObjectLastModified returns when the object stored under key was last written, and false if it does not exist.
+func (adapter *UploaderAdapter) ObjectLastModified(key string) (time.Time, bool, error) {
+	head, err := adapter.S3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(objectBucket(key)),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("failed to head %s: %w", key, err)
+	}
+	return aws.ToTime(head.LastModified), true, nil
+}
+
+// ListObjects lists every object stored under the given prefix in its bucket: the originals bucket for
+// IMAGE_ORIGINALS_PREFIX, the page bucket otherwise.
+func (adapter *UploaderAdapter) ListObjects(prefix string) ([]interfaces.StoredObject, error) {
+	return adapter.listObjects(objectBucket(prefix), prefix)
+}
+
+// listKeys lists the keys of every object stored under the given prefix in a bucket.
+func (adapter *UploaderAdapter) listKeys(bucket, prefix string) ([]string, error) {
+	objects, err := adapter.listObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys, nil
+}
+
+// listObjects lists every object stored under the given prefix in a bucket.
+func (adapter *UploaderAdapter) listObjects(bucket, prefix string) ([]interfaces.StoredObject, error) {
+	paginator := s3.NewListObjectsV2Paginator(adapter.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var objects []interfaces.StoredObject
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, interfaces.StoredObject{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// DeleteObject deletes an object from its bucket.
+func (adapter *UploaderAdapter) DeleteObject(key string) error {
+	_, err := adapter.S3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(objectBucket(key)),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// QuarantineObject moves an object under the given prefix, keeping its key after it. The object stays in its
+// bucket, so quarantined originals stay private.
+func (adapter *UploaderAdapter) QuarantineObject(key, prefix string) error {
+	bucket := objectBucket(key)
+	if err := adapter.copyObjectBetween(bucket, key, bucket, prefix+key, ""); err != nil {
+		return err
+	}
+	return adapter.DeleteObject(key)
+}
+
+// MoveObject moves an object of the S3 bucket to another key, keeping its metadata and tags.
+func (adapter *UploaderAdapter) MoveObject(key, newKey string) error {
+	if err := adapter.copyObject(key, newKey); err != nil {
+		return err
+	}
+	return adapter.DeleteObject(key)
+}
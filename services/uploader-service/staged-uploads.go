@@ -0,0 +1,79 @@
+package uploaderservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// StagedUploadKey returns the key under which the file uploaded for a job waits to be processed:
+// JOB_STAGING_PREFIX ("jobs/" by default), the job ID and the base name of the file.
+// The prefix must be outside of the reconciled prefixes, as staged files are not referenced by any page.
+func StagedUploadKey(jobID, fileName string) string {
+	prefix := os.Getenv("JOB_STAGING_PREFIX")
+	if prefix == "" {
+		prefix = "jobs/"
+	}
+	name := path.Base(fileName)
+	if name == "." || name == "/" {
+		name = "upload"
+	}
+	return prefix + jobID + "/" + name
+}
+
+// WriteObject stores a file as is in the S3 bucket, such as an upload staged until its job processes it.
+// It is encrypted with IMAGE_KMS_KEY_ID, when set, like the pages.
+// The body is streamed; it must be seekable, such as a file or a bytes.Reader, for the request to be signed.
+func (adapter *UploaderAdapter) WriteObject(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(os.Getenv("AWS_S3_BUCKET_NAME_S3")),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	}
+	if keyID := kmsKeyID(); keyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+	if _, err := adapter.S3Client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// ReadObject reads an object of the S3 bucket, such as a file stored by WriteObject.
+func (adapter *UploaderAdapter) ReadObject(ctx context.Context, key string) ([]byte, error) {
+	output, err := adapter.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(os.Getenv("AWS_S3_BUCKET_NAME_S3")),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return content, nil
+}
+
+// OpenObject opens an object of the S3 bucket for reading, so large files are streamed instead of read at once.
+// The caller closes the returned body.
+func (adapter *UploaderAdapter) OpenObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := adapter.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(os.Getenv("AWS_S3_BUCKET_NAME_S3")),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return output.Body, nil
+}
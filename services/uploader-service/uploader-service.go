@@ -4,81 +4,292 @@ package uploaderservice
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"strings"
+	"test/lambda/interfaces"
+	"test/lambda/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 )
 
 // UploaderAdapter represents a service for uploading images to an S3 bucket.
 type UploaderAdapter struct {
-	S3Client *s3.Client
+	S3Client    *s3.Client
+	KeyTemplate *utils.KeyTemplate
 }
 
 // NewUploaderAdapter creates a new UploaderAdapter instance configured with the AWS S3 client.
-// It returns a pointer to the UploaderAdapter or an error if the AWS configuration fails.
+// It returns a pointer to the UploaderAdapter or an error if the AWS configuration or the key template is invalid.
 func NewUploaderAdapter() (*UploaderAdapter, error) {
+	keyTemplate, err := ImageKeyTemplate()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOriginalsBucket(); err != nil {
+		return nil, err
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(os.Getenv("REGION")))
 	if err != nil {
 		return nil, err
 	}
 
 	s3Client := s3.NewFromConfig(cfg)
-	return &UploaderAdapter{S3Client: s3Client}, nil
+	return &UploaderAdapter{S3Client: s3Client, KeyTemplate: keyTemplate}, nil
+}
+
+// ImageKeyTemplate parses the layout of the keys of stored pages from IMAGE_KEY_TEMPLATE.
+// It defaults to utils.DefaultKeyTemplate, or utils.DefaultSHA256KeyTemplate when IMAGE_KEY_MODE is "sha256".
+// It returns an error if the template is invalid.
+func ImageKeyTemplate() (*utils.KeyTemplate, error) {
+	template := os.Getenv("IMAGE_KEY_TEMPLATE")
+	if template == "" {
+		template = utils.DefaultKeyTemplate
+		if ContentAddressed() {
+			template = utils.DefaultSHA256KeyTemplate
+		}
+	}
+
+	keyTemplate, err := utils.ParseKeyTemplate(template, ContentAddressed())
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAGE_KEY_TEMPLATE: %w", err)
+	}
+	return keyTemplate, nil
+}
+
+// UploadedImage represents an image stored in the S3 bucket by UploadImage.
+type UploadedImage struct {
+	Key            string // Key under which the image is stored.
+	ChecksumSHA256 string // Hex-encoded SHA-256 of the stored bytes.
+	Content        []byte // Stored bytes, after orientation, metadata stripping and normalization.
+	Deduplicated   bool   // Whether an identical object was already stored, so PutObject was skipped.
 }
 
 // UploadImage uploads the given image to the S3 bucket.
-// It takes the image bytes, tabloid ID, and order as parameters.
-// It returns the key under which the image is stored in the S3 bucket or an error if upload fails.
-func (adapter *UploaderAdapter) UploadImage(image []byte, tabloidID int64, order int) (string, error) {
+// It takes the image bytes and the page they belong to as parameters; ctx cancels the requests to S3.
+// Objects are stored with user metadata and tags describing the page (see PageRef), the Cache-Control of
+// IMAGE_CACHE_CONTROL and, when IMAGE_KMS_KEY_ID is set, SSE-KMS encryption with that key.
+// JPEG images are re-encoded upright and without metadata, and the image is transcoded to IMAGE_OUTPUT_FORMAT
+// (png or jpeg) when that variable is set; the key extension always matches the format actually stored.
+// When IMAGE_ORIGINALS_PREFIX is set, the untouched upload is also stored under that prefix, in IMAGE_ORIGINALS_BUCKET.
+// The key follows the KeyTemplate of the adapter. When IMAGE_KEY_MODE is "sha256", the key is derived from
+// the content and an existing object is reused.
+// It returns the stored image or an error if upload fails.
+func (adapter *UploaderAdapter) UploadImage(ctx context.Context, image []byte, ref PageRef) (*UploadedImage, error) {
 	if image == nil {
-		return "", nil
+		return nil, errors.New("empty image")
 	}
 
 	if err := adapter.validateImage(image); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	key := adapter.getImageKey(image, tabloidID, order)
+	// Apply the EXIF orientation and strip the metadata (GPS included) of JPEG photos
+	original := image
+	image, err := utils.StripJpegMetadata(image)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := adapter.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
-		Bucket:      aws.String(os.Getenv("AWS_S3_BUCKET_NAME_S3")),
-		Key:         aws.String(key),
-		ContentType: aws.String(http.DetectContentType(image)),
-		Body:        bytes.NewReader(image),
-	})
+	// Transcode to the canonical output format, if one is configured
+	image, err = utils.NormalizeImage(image, os.Getenv("IMAGE_OUTPUT_FORMAT"))
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded := &UploadedImage{
+		Key:            adapter.getImageKey(image, ref.Tabloid, ref.Order),
+		ChecksumSHA256: checksumSHA256(image),
+		Content:        image,
+	}
+
+	uploaded.Deduplicated, err = adapter.storeObject(ctx, uploaded.Key, image, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the untouched upload in the private bucket, if originals are kept
+	if prefix := os.Getenv("IMAGE_ORIGINALS_PREFIX"); prefix != "" {
+		if _, err := adapter.storeObject(ctx, originalKey(prefix, uploaded.Key, adapter.getImageExtension(original)), original, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	return uploaded, nil
+}
+
+// originalKey derives the key of the untouched upload from the key of the stored image,
+// moving it under prefix and using the extension of the upload.
+func originalKey(prefix, key, extension string) string {
+	return prefix + strings.TrimSuffix(key, path.Ext(key)) + extension
+}
+
+// checkOriginalsBucket checks that the untouched uploads, when IMAGE_ORIGINALS_PREFIX is set, are kept in
+// IMAGE_ORIGINALS_BUCKET: the page bucket is public, and originals keep what stored pages strip, such as the GPS
+// position of photos.
+func checkOriginalsBucket() error {
+	if os.Getenv("IMAGE_ORIGINALS_PREFIX") == "" {
+		return nil
+	}
+	bucket := os.Getenv("IMAGE_ORIGINALS_BUCKET")
+	if bucket == "" || bucket == os.Getenv("AWS_S3_BUCKET_NAME_S3") {
+		return errors.New("IMAGE_ORIGINALS_PREFIX requires IMAGE_ORIGINALS_BUCKET, a private bucket other than AWS_S3_BUCKET_NAME_S3")
+	}
+	return nil
+}
+
+// isOriginalKey reports whether key is the key of an untouched upload.
+func isOriginalKey(key string) bool {
+	prefix := os.Getenv("IMAGE_ORIGINALS_PREFIX")
+	return prefix != "" && strings.HasPrefix(key, prefix)
+}
+
+// objectBucket returns the bucket of the object stored under key: IMAGE_ORIGINALS_BUCKET for the untouched
+// uploads and AWS_S3_BUCKET_NAME_S3, the page bucket, for everything else.
+func objectBucket(key string) string {
+	if isOriginalKey(key) {
+		return os.Getenv("IMAGE_ORIGINALS_BUCKET")
+	}
+	return os.Getenv("AWS_S3_BUCKET_NAME_S3")
+}
+
+// storeObject stores the image of the given page under the given key. In content-addressed mode the key identifies
+// the content, so PutObject is skipped when the object already exists; it returns true when that happens.
+// The reused object is refreshed, so the reconciliation does not take it for an old orphan before the page commits.
+func (adapter *UploaderAdapter) storeObject(ctx context.Context, key string, image []byte, ref PageRef) (bool, error) {
+	if ContentAddressed() {
+		exists, err := adapter.refreshObject(ctx, key)
+		if err != nil {
+			fmt.Println(err)
+			return false, errors.New("ERROR_UPLOAD_IMAGE")
+		}
+		if exists {
+			if err := adapter.extendValidityTag(ctx, key, ref); err != nil {
+				fmt.Println(err)
+				return false, errors.New("ERROR_UPLOAD_IMAGE")
+			}
+			return true, nil
+		}
+	}
+	return false, adapter.putObject(ctx, key, image, ref)
+}
+
+// putObject stores the image of the given page under the given key in its bucket (see objectBucket).
+// The SHA-256 checksum of the image is sent along, so S3 rejects the upload if the bytes it receives differ.
+// Images of IMAGE_MULTIPART_THRESHOLD bytes or more are sent with a multipart upload.
+func (adapter *UploaderAdapter) putObject(ctx context.Context, key string, image []byte, ref PageRef) error {
+	checksum := sha256.Sum256(image)
+	input := &s3.PutObjectInput{
+		Bucket:            aws.String(objectBucket(key)),
+		Key:               aws.String(key),
+		ContentType:       aws.String(http.DetectContentType(image)),
+		CacheControl:      aws.String(objectCacheControl()),
+		Metadata:          ref.objectMetadata(),
+		Tagging:           aws.String(utils.EncodeObjectTags(ref.objectTags())),
+		Body:              bytes.NewReader(image),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		ChecksumSHA256:    aws.String(base64.StdEncoding.EncodeToString(checksum[:])),
+	}
+	if keyID := kmsKeyID(); keyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(keyID)
+	}
+
+	var err error
+	if int64(len(image)) >= multipartThreshold() {
+		err = adapter.putObjectMultipart(ctx, input, image)
+	} else {
+		_, err = adapter.S3Client.PutObject(ctx, input)
+	}
 	if err != nil {
 		fmt.Println(err)
-		return "", errors.New("ERROR_UPLOAD_IMAGE")
+		return errors.New("ERROR_UPLOAD_IMAGE")
 	}
+	return nil
+}
 
-	return key, nil
+// checksumSHA256 returns the hex-encoded SHA-256 of the image.
+func checksumSHA256(image []byte) string {
+	checksum := sha256.Sum256(image)
+	return hex.EncodeToString(checksum[:])
 }
 
-// getImageKey generates a unique key for the image based on tabloid ID, order, and UUID.
-func (adapter *UploaderAdapter) getImageKey(image []byte, tabloidID int64, order int) string {
-	extension := adapter.getImageExtension(image)
-	pagina := order + 1
-	uuid := uuid.New()
-	return fmt.Sprintf("RPA/v3/%d/campanha-%d-%s-pagina-%d%s", tabloidID, tabloidID, uuid, pagina, extension)
+// ContentAddressed reports whether IMAGE_KEY_MODE selects content-addressed keys, which different tabloids may share.
+func ContentAddressed() bool {
+	return os.Getenv("IMAGE_KEY_MODE") == "sha256"
 }
 
-// validateImage checks if the image has a valid content type.
-// It returns an error if the image type is not supported.
-func (adapter *UploaderAdapter) validateImage(image []byte) error {
-	contentType := http.DetectContentType(image)
-	if contentType != "image/png" && contentType != "image/jpg" && contentType != "image/jpeg" {
-		return errors.New("invalid image type")
+// HeadBucket checks that the S3 bucket exists and can be reached with the credentials of the function.
+func (adapter *UploaderAdapter) HeadBucket(ctx context.Context) error {
+	bucket := os.Getenv("AWS_S3_BUCKET_NAME_S3")
+	if _, err := adapter.S3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("failed to head bucket %s: %w", bucket, err)
 	}
 	return nil
 }
 
+// ObjectExists checks whether an object with the given key is stored in the S3 bucket.
+// It returns false without error when the object does not exist.
+func (adapter *UploaderAdapter) ObjectExists(key string) (bool, error) {
+	return adapter.objectExists(context.Background(), key)
+}
+
+// objectExists checks whether an object with the given key is stored in the S3 bucket, within ctx.
+func (adapter *UploaderAdapter) objectExists(ctx context.Context, key string) (bool, error) {
+	_, err := adapter.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(os.Getenv("AWS_S3_BUCKET_NAME_S3")),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// getImageKey generates the key of the image from the KeyTemplate of the adapter, using the tabloid,
+// the page number, a new UUID and the SHA-256 of the image. In content-addressed mode the template only uses
+// the SHA-256, so identical pages share one object whatever tabloid they belong to.
+func (adapter *UploaderAdapter) getImageKey(image []byte, tabloid interfaces.Tabloid, order int) string {
+	return adapter.KeyTemplate.Render(utils.KeyFields{
+		TabloidID: tabloid.ID,
+		RegionID:  tabloid.RegiaoID,
+		StartDate: tabloid.DtInicioVigencia,
+		EndDate:   tabloid.DtFimVigencia,
+		Page:      order + 1,
+		SHA256:    checksumSHA256(image),
+		UUID:      uuid.New().String(),
+		Extension: adapter.getImageExtension(image),
+	})
+}
+
+// ValidateImage checks an image with the same rules UploadImage applies before storing it.
+// It returns an error if the image type is not supported.
+func (adapter *UploaderAdapter) ValidateImage(image []byte) error {
+	return adapter.validateImage(image)
+}
+
+// validateImage checks the image with the validation pipeline of utils.ValidateImageContent:
+// PNG, JPEG, WebP and static GIF images that fully decode within the IMAGE_* limits are accepted.
+// It returns a *utils.ImageValidationError describing why the image was rejected.
+func (adapter *UploaderAdapter) validateImage(image []byte) error {
+	return utils.ValidateImageContent(image, utils.ImageLimitsFromEnv())
+}
+
 // getImageExtension extracts the file extension from the image content type.
 func (adapter *UploaderAdapter) getImageExtension(image []byte) string {
 	contentType := http.DetectContentType(image)
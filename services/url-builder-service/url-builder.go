@@ -0,0 +1,203 @@
+// Package urlbuilderservice provides functionality for building the public URLs of stored objects at read time.
+package urlbuilderservice
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"test/lambda/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectRef identifies a stored object and the region it is published in.
+type ObjectRef struct {
+	StorageID string    // ID of the storage backend holding the object.
+	Key       string    // Key of the object in the storage backend.
+	RegionID  int       // Region of the tabloid the object belongs to.
+	NotAfter  time.Time // Instant signed URLs must expire by at the latest (end of validity), zero for no limit.
+}
+
+// URLBuilder resolves the URL clients use to fetch a stored object.
+type URLBuilder interface {
+	BuildURL(object ObjectRef) (string, error)
+}
+
+// NewURLBuilder creates the URLBuilder selected by the URL_BUILDER_MODE environment variable:
+//
+//   - "cdn" (default): CDN_URL followed by the key.
+//   - "region": the host configured for the region in REGION_CDN_HOSTS ("144=https://sp.cdn.example.com/,..."),
+//     falling back to CDN_URL.
+//   - "presigned": S3 presigned GET URLs valid for URL_EXPIRY_SECONDS (900 by default).
+//   - "cloudfront": CloudFront signed URLs under CLOUDFRONT_URL (CDN_URL by default) valid for URL_EXPIRY_SECONDS,
+//     signed with CLOUDFRONT_KEY_PAIR_ID and the PEM private key in CLOUDFRONT_PRIVATE_KEY.
+//
+// Signed URLs never outlive ObjectRef.NotAfter. In private mode (see PrivateMode) only the signing modes are accepted.
+// It returns an error if the configuration is invalid.
+func NewURLBuilder() (URLBuilder, error) {
+	mode := os.Getenv("URL_BUILDER_MODE")
+	if PrivateMode() && mode != "presigned" && mode != "cloudfront" {
+		return nil, fmt.Errorf("IMAGE_ACCESS=private requires URL_BUILDER_MODE presigned or cloudfront, got %q", mode)
+	}
+
+	switch mode {
+	case "", "cdn":
+		return &CDNPrefixBuilder{Prefix: os.Getenv("CDN_URL")}, nil
+	case "region":
+		hosts, err := parseRegionHosts(os.Getenv("REGION_CDN_HOSTS"))
+		if err != nil {
+			return nil, err
+		}
+		return &RegionCDNBuilder{Hosts: hosts, Default: os.Getenv("CDN_URL")}, nil
+	case "presigned":
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(os.Getenv("REGION")))
+		if err != nil {
+			return nil, err
+		}
+		return &S3PresignedBuilder{
+			Client: s3.NewPresignClient(s3.NewFromConfig(cfg)),
+			Bucket: os.Getenv("AWS_S3_BUCKET_NAME_S3"),
+			Expiry: URLExpiry(),
+		}, nil
+	case "cloudfront":
+		key, err := utils.ParseRSAPrivateKey([]byte(os.Getenv("CLOUDFRONT_PRIVATE_KEY")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CLOUDFRONT_PRIVATE_KEY: %w", err)
+		}
+		domain := os.Getenv("CLOUDFRONT_URL")
+		if domain == "" {
+			domain = os.Getenv("CDN_URL")
+		}
+		return &CloudFrontSignedBuilder{
+			Domain:     domain,
+			KeyPairID:  os.Getenv("CLOUDFRONT_KEY_PAIR_ID"),
+			PrivateKey: key,
+			Expiry:     URLExpiry(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid URL_BUILDER_MODE: %s", mode)
+	}
+}
+
+// CDNPrefixBuilder builds URLs by prefixing keys with a CDN base URL.
+type CDNPrefixBuilder struct {
+	Prefix string // Base URL of the CDN, ending with a slash.
+}
+
+// BuildURL returns the CDN URL of the object.
+func (b *CDNPrefixBuilder) BuildURL(object ObjectRef) (string, error) {
+	return b.Prefix + object.Key, nil
+}
+
+// RegionCDNBuilder builds URLs using a CDN host per region.
+type RegionCDNBuilder struct {
+	Hosts   map[int]string // Base URL of the CDN of each region.
+	Default string         // Base URL used for regions without a host of their own.
+}
+
+// BuildURL returns the URL of the object on the CDN of its region.
+func (b *RegionCDNBuilder) BuildURL(object ObjectRef) (string, error) {
+	if host, ok := b.Hosts[object.RegionID]; ok {
+		return host + object.Key, nil
+	}
+	return b.Default + object.Key, nil
+}
+
+// S3PresignedBuilder builds time-limited S3 presigned GET URLs.
+type S3PresignedBuilder struct {
+	Client *s3.PresignClient
+	Bucket string
+	Expiry time.Duration
+}
+
+// BuildURL returns a presigned GET URL of the object, expiring by object.NotAfter at the latest.
+// It is signed at utils.SignedURLTime, so the same URL is returned for a while.
+func (b *S3PresignedBuilder) BuildURL(object ObjectRef) (string, error) {
+	now := time.Now()
+	signedAt := utils.SignedURLTime(now, b.Expiry)
+	expires := utils.SignedURLExpiry(signedAt, b.Expiry, object.NotAfter)
+	if expires.Sub(now) < time.Second {
+		return "", fmt.Errorf("object %s is no longer valid", object.Key)
+	}
+	request, err := b.Client.PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(object.Key),
+	}, s3.WithPresignExpires(expires.Sub(signedAt)), func(options *s3.PresignOptions) {
+		options.Presigner = fixedTimePresigner{signer: v4.NewSigner(func(signer *v4.SignerOptions) {
+			signer.DisableURIPathEscaping = true
+		}), signingTime: signedAt}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return request.URL, nil
+}
+
+// fixedTimePresigner presigns requests as signed at signingTime, instead of the time they are presigned at.
+type fixedTimePresigner struct {
+	signer      *v4.Signer
+	signingTime time.Time
+}
+
+// PresignHTTP presigns the request with the fixed signing time.
+func (p fixedTimePresigner) PresignHTTP(ctx context.Context, credentials aws.Credentials, r *http.Request, payloadHash string,
+	service string, region string, _ time.Time, optFns ...func(*v4.SignerOptions)) (string, http.Header, error) {
+	return p.signer.PresignHTTP(ctx, credentials, r, payloadHash, service, region, p.signingTime, optFns...)
+}
+
+// CloudFrontSignedBuilder builds time-limited CloudFront signed URLs with a canned policy.
+type CloudFrontSignedBuilder struct {
+	Domain     string           // Base URL of the CloudFront distribution, ending with a slash.
+	KeyPairID  string           // ID of the CloudFront public key matching PrivateKey.
+	PrivateKey *rsa.PrivateKey  // Key the URLs are signed with.
+	Expiry     time.Duration    // Lifetime of the URLs.
+	Now        func() time.Time // Clock used to compute expiries, time.Now when nil.
+}
+
+// BuildURL returns a signed CloudFront URL of the object, expiring by object.NotAfter at the latest.
+// It is signed at utils.SignedURLTime, so the same URL is returned for a while.
+func (b *CloudFrontSignedBuilder) BuildURL(object ObjectRef) (string, error) {
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	signedAt := utils.SignedURLTime(now(), b.Expiry)
+	return utils.SignCloudFrontURL(b.Domain+object.Key, b.KeyPairID, b.PrivateKey, utils.SignedURLExpiry(signedAt, b.Expiry, object.NotAfter))
+}
+
+// PrivateMode reports whether IMAGE_ACCESS is "private": objects are not public, read endpoints only hand out
+// signed URLs, and pages of tabloids are withheld from the public feeds until their validity starts.
+func PrivateMode() bool {
+	return os.Getenv("IMAGE_ACCESS") == "private"
+}
+
+// URLExpiry returns the lifetime of signed URLs, from URL_EXPIRY_SECONDS (900 by default).
+func URLExpiry() time.Duration {
+	return time.Duration(utils.GetEnvInt("URL_EXPIRY_SECONDS", 900)) * time.Second
+}
+
+// parseRegionHosts parses REGION_CDN_HOSTS, a comma-separated list of region=base URL pairs.
+func parseRegionHosts(value string) (map[int]string, error) {
+	hosts := map[int]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		regionID, host, found := strings.Cut(pair, "=")
+		id, err := strconv.Atoi(strings.TrimSpace(regionID))
+		if !found || err != nil || strings.TrimSpace(host) == "" {
+			return nil, fmt.Errorf("invalid REGION_CDN_HOSTS entry: %q", pair)
+		}
+		hosts[id] = strings.TrimSpace(host)
+	}
+	return hosts, nil
+}
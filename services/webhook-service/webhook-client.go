@@ -0,0 +1,103 @@
+// Package webhookservice provides the client that delivers domain events to the webhooks of partner apps.
+package webhookservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"test/lambda/interfaces"
+	"test/lambda/utils"
+	"time"
+)
+
+// WebhookSender delivers domain events to webhook URLs.
+// It returns the status code of the answer (0 if there was none) and an error if the delivery failed.
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret string, event interfaces.DomainEvent) (int, error)
+}
+
+// WebhookClient POSTs signed events to webhook URLs.
+type WebhookClient struct {
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+// NewWebhookClient creates a WebhookClient whose requests time out after WEBHOOK_TIMEOUT_SECONDS (10 by default).
+// Webhook URLs are given by partners, so the client only connects to public addresses: the resolved address is
+// checked when dialing, which covers redirects and DNS names pointing inside the VPC.
+func NewWebhookClient() *WebhookClient {
+	timeout := time.Duration(utils.GetEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second
+	dialer := &net.Dialer{Timeout: timeout, Control: checkPublicAddress}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: timeout,
+	}
+	return &WebhookClient{HTTPClient: &http.Client{Timeout: timeout, Transport: transport}, Now: time.Now}
+}
+
+// ErrAddressNotAllowed is returned when a webhook URL resolves to an address the client does not connect to.
+var ErrAddressNotAllowed = errors.New("webhook address not allowed")
+
+// checkPublicAddress rejects the connections to loopback, link-local (such as the instance metadata endpoint),
+// private, multicast and unspecified addresses. It is the Control of the dialer, so it sees the resolved address.
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, ip)
+	}
+	return nil
+}
+
+// Send POSTs an event as JSON to url, signed with secret: the signature, the timestamp, the type and the
+// deduplication ID of the event are sent as the utils.Webhook*Header headers.
+// It returns the status code of the answer and an error if there was no answer or it was not a 2xx.
+func (c *WebhookClient) Send(ctx context.Context, url, secret string, event interfaces.DomainEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	timestamp := c.Now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(utils.WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(secret, timestamp, body))
+	request.Header.Set(utils.WebhookEventHeader, event.Type)
+	request.Header.Set(utils.WebhookIDHeader, event.DeduplicationID)
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post %s: %w", event.DeduplicationID, err)
+	}
+	defer response.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"test/lambda/interfaces"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetAuditContext extracts the actor and the request ID of the current request.
+// The username comes from the x-username header, which serverless.yml fills with
+// $context.authorizer.username. The request ID comes from the API Gateway context,
+// falling back to the X-Request-Id header and finally to a generated UUID when running locally.
+//
+// Example:
+//
+//	auditContext := GetAuditContext(c)
+//	fmt.Println("Mutation performed by", auditContext.Username, "in request", auditContext.RequestID)
+func GetAuditContext(c *gin.Context) interfaces.AuditContext {
+	auditContext := interfaces.AuditContext{
+		Username:  c.GetHeader("x-username"),
+		RequestID: c.GetHeader("X-Request-Id"),
+	}
+
+	if apiGwContext, ok := core.GetAPIGatewayV2ContextFromContext(c.Request.Context()); ok && apiGwContext.RequestID != "" {
+		auditContext.RequestID = apiGwContext.RequestID
+	}
+	if auditContext.RequestID == "" {
+		auditContext.RequestID = uuid.New().String()
+	}
+	if auditContext.Username == "" {
+		auditContext.Username = "unknown"
+	}
+
+	return auditContext
+}
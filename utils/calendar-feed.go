@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// atomFeed is the root element of an Atom 1.0 document.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Summary string      `xml:"summary"`
+	Content atomContent `xml:"content"`
+	Link    []atomLink  `xml:"link"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// BuildAtomFeed renders the tabloids of a region feed as an Atom 1.0 document.
+// Each tabloid becomes one entry whose content lists its page images; selfURL is the URL the feed is served from.
+//
+// Example:
+//
+//	document, err := BuildAtomFeed(feed, "https://api.example.com/regions/144/feed.atom")
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", document)
+func BuildAtomFeed(feed interfaces.RegionFeed, selfURL string) ([]byte, error) {
+	document := atomFeed{
+		ID:      fmt.Sprintf("urn:tabloide:regiao:%d", feed.RegionID),
+		Title:   fmt.Sprintf("Tabloides da região %d", feed.RegionID),
+		Updated: formatAtomDate(FeedLastModified(feed)),
+		Link:    []atomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+	}
+
+	for _, tabloid := range feed.Tabloids {
+		entry := atomEntry{
+			ID:      fmt.Sprintf("urn:tabloide:%d", tabloid.ID),
+			Title:   tabloid.Nome,
+			Updated: formatAtomDate(tabloidUpdated(tabloid.Tabloid)),
+			Summary: fmt.Sprintf("Válido de %s a %s", tabloid.DtInicioVigencia.Format("02/01/2006"), tabloid.DtFimVigencia.Format("02/01/2006")),
+			Content: atomContent{Type: "html", Body: pagesHTML(tabloid)},
+		}
+		for _, page := range tabloid.Pages {
+			entry.Link = append(entry.Link, atomLink{Href: page.ImageURL, Rel: "enclosure", Type: imageMimeType(page.ImageURL)})
+		}
+		if len(tabloid.Pages) > 0 {
+			entry.Link = append(entry.Link, atomLink{Href: tabloid.Pages[0].ImageURL, Rel: "alternate"})
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal atom feed: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// BuildICalendar renders the tabloids of a region feed as an iCalendar (RFC 5545) document.
+// Each tabloid becomes one all-day event spanning dt_inicio_vigencia..dt_fim_vigencia, with its pages attached.
+//
+// Example:
+//
+//	document := BuildICalendar(feed, time.Now())
+//	c.Data(http.StatusOK, "text/calendar; charset=utf-8", document)
+func BuildICalendar(feed interfaces.RegionFeed, now time.Time) []byte {
+	var builder strings.Builder
+	writeLine := func(line string) {
+		builder.WriteString(foldICalendarLine(line))
+		builder.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//tabloid-go-poc//Tabloides//PT")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:" + escapeICalendarText(fmt.Sprintf("Tabloides da região %d", feed.RegionID)))
+
+	for _, tabloid := range feed.Tabloids {
+		// All-day events use an exclusive DTEND, so the last validity day is included
+		end := time.Date(tabloid.DtFimVigencia.Year(), tabloid.DtFimVigencia.Month(), tabloid.DtFimVigencia.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+
+		writeLine("BEGIN:VEVENT")
+		writeLine(fmt.Sprintf("UID:tabloide-%d@tabloid-go-poc", tabloid.ID))
+		writeLine("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		writeLine("LAST-MODIFIED:" + tabloidUpdated(tabloid.Tabloid).UTC().Format("20060102T150405Z"))
+		writeLine("DTSTART;VALUE=DATE:" + tabloid.DtInicioVigencia.Format("20060102"))
+		writeLine("DTEND;VALUE=DATE:" + end.Format("20060102"))
+		writeLine("SUMMARY:" + escapeICalendarText(tabloid.Nome))
+		writeLine("TRANSP:TRANSPARENT")
+		if len(tabloid.Pages) > 0 {
+			writeLine("URL:" + tabloid.Pages[0].ImageURL)
+		}
+		for _, page := range tabloid.Pages {
+			writeLine(fmt.Sprintf("ATTACH;FMTTYPE=%s:%s", imageMimeType(page.ImageURL), page.ImageURL))
+		}
+		writeLine("END:VEVENT")
+	}
+
+	writeLine("END:VCALENDAR")
+	return []byte(builder.String())
+}
+
+// foldICalendarLine folds content lines longer than 75 octets, as RFC 5545 requires,
+// without splitting UTF-8 sequences.
+func foldICalendarLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var builder strings.Builder
+	lineLength := 0
+	for _, r := range line {
+		size := len(string(r))
+		if lineLength+size > limit {
+			builder.WriteString("\r\n ")
+			lineLength = 1
+		}
+		builder.WriteRune(r)
+		lineLength += size
+	}
+	return builder.String()
+}
+
+// escapeICalendarText escapes a TEXT value as RFC 5545 requires.
+func escapeICalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// pagesHTML renders the pages of a tabloid as a list of images.
+func pagesHTML(tabloid interfaces.FeedTabloid) string {
+	var builder strings.Builder
+	for _, page := range tabloid.Pages {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(page.ImageURL))
+		fmt.Fprintf(&builder, `<p><img src="%s" alt="Página %d"/></p>`, escaped.String(), page.Order+1)
+	}
+	return builder.String()
+}
+
+// imageMimeType guesses the MIME type of a page image from its extension.
+func imageMimeType(imageURL string) string {
+	// Signed URLs carry their signature in the query string
+	imageURL, _, _ = strings.Cut(imageURL, "?")
+	switch {
+	case strings.HasSuffix(imageURL, ".png"):
+		return "image/png"
+	case strings.HasSuffix(imageURL, ".webp"):
+		return "image/webp"
+	case strings.HasSuffix(imageURL, ".gif"):
+		return "image/gif"
+	default:
+		return "image/jpeg"
+	}
+}
+
+// tabloidUpdated returns dt_alteracao, or dt_cadastro for tabloids never changed.
+func tabloidUpdated(tabloid interfaces.Tabloid) time.Time {
+	if tabloid.DtAlteracao.IsZero() {
+		return tabloid.DtCadastro
+	}
+	return tabloid.DtAlteracao
+}
+
+// FeedLastModified returns the most recent dt_alteracao (or dt_cadastro, for tabloids never changed) of the feed.
+func FeedLastModified(feed interfaces.RegionFeed) time.Time {
+	var updated time.Time
+	for _, tabloid := range feed.Tabloids {
+		if tabloidUpdated(tabloid.Tabloid).After(updated) {
+			updated = tabloidUpdated(tabloid.Tabloid)
+		}
+	}
+	return updated
+}
+
+// formatAtomDate formats a date as an RFC 3339 timestamp.
+func formatAtomDate(date time.Time) string {
+	return date.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/xml"
+	"strings"
+	"test/lambda/interfaces"
+	"testing"
+	"time"
+)
+
+func sampleRegionFeed() interfaces.RegionFeed {
+	return interfaces.RegionFeed{
+		RegionID: 144,
+		Tabloids: []interfaces.FeedTabloid{{
+			Tabloid: interfaces.Tabloid{
+				ID:               42,
+				Nome:             "Ofertas, semana; 15",
+				DtInicioVigencia: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+				DtFimVigencia:    time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC),
+				DtCadastro:       time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC),
+			},
+			Pages: []interfaces.Page{
+				{TabloidID: 42, ImageURL: "https://cdn.example.com/RPA/v3/42/pagina-1.png", Order: 0},
+				{TabloidID: 42, ImageURL: "https://cdn.example.com/RPA/v3/42/pagina-2.jpeg", Order: 1},
+			},
+		}},
+	}
+}
+
+func TestBuildAtomFeed(t *testing.T) {
+	document, err := BuildAtomFeed(sampleRegionFeed(), "https://api.example.com/regions/144/feed.atom")
+	if err != nil {
+		t.Fatalf("BuildAtomFeed returned an error: %v", err)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(document, &feed); err != nil {
+		t.Fatalf("BuildAtomFeed returned invalid XML: %v", err)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("BuildAtomFeed returned %d entries, expected 1", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.ID != "urn:tabloide:42" || entry.Updated != "2024-04-01T09:30:00Z" {
+		t.Errorf("BuildAtomFeed returned entry %+v", entry)
+	}
+	if len(entry.Link) != 3 || entry.Link[0].Rel != "enclosure" || entry.Link[0].Type != "image/png" {
+		t.Errorf("BuildAtomFeed returned links %+v", entry.Link)
+	}
+}
+
+func TestBuildICalendar(t *testing.T) {
+	document := string(BuildICalendar(sampleRegionFeed(), time.Date(2024, 4, 9, 12, 0, 0, 0, time.UTC)))
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:tabloide-42@tabloid-go-poc\r\n",
+		"DTSTART;VALUE=DATE:20240408\r\n",
+		"DTEND;VALUE=DATE:20240411\r\n",
+		`SUMMARY:Ofertas\, semana\; 15` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(document, expected) {
+			t.Errorf("BuildICalendar output does not contain %q:\n%s", expected, document)
+		}
+	}
+
+	for _, line := range strings.Split(document, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("BuildICalendar returned a line longer than 75 octets: %q", line)
+		}
+	}
+}
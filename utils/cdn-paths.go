@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"test/lambda/interfaces"
+)
+
+// CDNPaths collects the CDN paths a change makes stale, so they are invalidated in one batch.
+// Paths are added once, however many times they are given.
+type CDNPaths struct {
+	ImagePrefix string // Path the image keys are served under, such as "/" or "/images/".
+	FeedPrefix  string // Path the region feeds are served under, such as "" or "/dev".
+
+	paths map[string]bool
+}
+
+// NewCDNPaths creates an empty CDNPaths serving images under imagePrefix and the region feeds under feedPrefix.
+func NewCDNPaths(imagePrefix, feedPrefix string) *CDNPaths {
+	if !strings.HasSuffix(imagePrefix, "/") {
+		imagePrefix += "/"
+	}
+	return &CDNPaths{
+		ImagePrefix: imagePrefix,
+		FeedPrefix:  strings.TrimSuffix(feedPrefix, "/"),
+		paths:       map[string]bool{},
+	}
+}
+
+// CDNPathsFromEnv creates an empty CDNPaths whose image prefix is the path of CLOUDFRONT_URL, or CDN_URL when it is
+// not set, and whose feed prefix is CDN_FEED_PATH_PREFIX.
+func CDNPathsFromEnv() *CDNPaths {
+	base := os.Getenv("CLOUDFRONT_URL")
+	if base == "" {
+		base = os.Getenv("CDN_URL")
+	}
+	imagePrefix := "/"
+	if parsed, err := url.Parse(base); err == nil && parsed.Path != "" {
+		imagePrefix = parsed.Path
+	}
+	return NewCDNPaths(imagePrefix, os.Getenv("CDN_FEED_PATH_PREFIX"))
+}
+
+// AddPage adds the paths of the image of a page and of its resized copies.
+func (p *CDNPaths) AddPage(page interfaces.Page) {
+	p.AddKey(page.ImageKey)
+	for _, variant := range page.Variants {
+		p.AddKey(variant.Key)
+	}
+}
+
+// AddKey adds the path of a stored object. Empty keys are ignored.
+func (p *CDNPaths) AddKey(key string) {
+	if key != "" {
+		p.add(p.ImagePrefix + strings.TrimPrefix(key, "/"))
+	}
+}
+
+// AddRegionFeeds adds the paths of the feeds of a region: the JSON feed, the Atom feed and the calendar.
+func (p *CDNPaths) AddRegionFeeds(regionID int) {
+	for _, document := range []string{"tabloids", "feed.atom", "calendar.ics"} {
+		p.add(fmt.Sprintf("%s/regions/%d/%s", p.FeedPrefix, regionID, document))
+	}
+}
+
+// Paths returns the paths added so far, escaped and sorted.
+func (p *CDNPaths) Paths() []string {
+	paths := make([]string, 0, len(p.paths))
+	for path := range p.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Len returns the number of paths added so far.
+func (p *CDNPaths) Len() int {
+	return len(p.paths)
+}
+
+// add adds one path, escaped as CDNs expect it.
+func (p *CDNPaths) add(path string) {
+	p.paths[(&url.URL{Path: path}).EscapedPath()] = true
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"reflect"
+	"test/lambda/interfaces"
+	"testing"
+)
+
+func TestCDNPaths(t *testing.T) {
+	paths := NewCDNPaths("/images", "/dev/")
+	page := interfaces.Page{
+		ImageKey: "RPA/v3/42/página 1.png",
+		Variants: []interfaces.PageVariant{{Width: 320, Key: "RPA/v3/42/página 1-320.webp"}},
+	}
+	paths.AddPage(page)
+	paths.AddPage(page)
+	paths.AddKey("")
+	paths.AddRegionFeeds(144)
+
+	expected := []string{
+		"/dev/regions/144/calendar.ics",
+		"/dev/regions/144/feed.atom",
+		"/dev/regions/144/tabloids",
+		"/images/RPA/v3/42/p%C3%A1gina%201-320.webp",
+		"/images/RPA/v3/42/p%C3%A1gina%201.png",
+	}
+	if got := paths.Paths(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Paths = %v, expected %v", got, expected)
+	}
+	if paths.Len() != len(expected) {
+		t.Errorf("Len = %d, expected %d", paths.Len(), len(expected))
+	}
+}
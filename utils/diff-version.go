@@ -0,0 +1,57 @@
+package utils
+
+import "test/lambda/interfaces"
+
+// DiffTabloidVersions compares two versions of a tabloid field by field and page by page.
+// It returns the metadata fields whose values differ and the page positions whose images differ.
+//
+// Example:
+//
+//	diff := DiffTabloidVersions(versionOne, versionTwo)
+//	for _, change := range diff.Fields {
+//	    fmt.Printf("%s: %v -> %v\n", change.Field, change.From, change.To)
+//	}
+func DiffTabloidVersions(from, to interfaces.TabloidVersion) interfaces.VersionDiff {
+	diff := interfaces.VersionDiff{
+		TabloidID: to.TabloidID,
+		From:      from.Version,
+		To:        to.Version,
+		Fields:    []interfaces.VersionFieldChange{},
+		Pages:     []interfaces.VersionPageChange{},
+	}
+
+	if from.Nome != to.Nome {
+		diff.Fields = append(diff.Fields, interfaces.VersionFieldChange{Field: "nome", From: from.Nome, To: to.Nome})
+	}
+	if from.RegiaoID != to.RegiaoID {
+		diff.Fields = append(diff.Fields, interfaces.VersionFieldChange{Field: "regiao_id", From: from.RegiaoID, To: to.RegiaoID})
+	}
+	if !from.DtInicioVigencia.Equal(to.DtInicioVigencia) {
+		diff.Fields = append(diff.Fields, interfaces.VersionFieldChange{Field: "dt_inicio_vigencia", From: from.DtInicioVigencia, To: to.DtInicioVigencia})
+	}
+	if !from.DtFimVigencia.Equal(to.DtFimVigencia) {
+		diff.Fields = append(diff.Fields, interfaces.VersionFieldChange{Field: "dt_fim_vigencia", From: from.DtFimVigencia, To: to.DtFimVigencia})
+	}
+	if from.Ativo != to.Ativo {
+		diff.Fields = append(diff.Fields, interfaces.VersionFieldChange{Field: "ativo", From: from.Ativo, To: to.Ativo})
+	}
+
+	pageCount := len(from.Pages)
+	if len(to.Pages) > pageCount {
+		pageCount = len(to.Pages)
+	}
+	for order := 0; order < pageCount; order++ {
+		var fromPage, toPage string
+		if order < len(from.Pages) {
+			fromPage = from.Pages[order]
+		}
+		if order < len(to.Pages) {
+			toPage = to.Pages[order]
+		}
+		if fromPage != toPage {
+			diff.Pages = append(diff.Pages, interfaces.VersionPageChange{Order: order, From: fromPage, To: toPage})
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"test/lambda/interfaces"
+	"testing"
+	"time"
+)
+
+func TestDiffTabloidVersions_NoChanges(t *testing.T) {
+	version := interfaces.TabloidVersion{
+		Version:          1,
+		Nome:             "Tabloide Marcos",
+		RegiaoID:         144,
+		DtInicioVigencia: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+		DtFimVigencia:    time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC),
+		Pages:            []string{"page-1.png", "page-2.png"},
+	}
+	diff := DiffTabloidVersions(version, version)
+	if len(diff.Fields) != 0 || len(diff.Pages) != 0 {
+		t.Errorf("DiffTabloidVersions returned changes for identical versions: %+v", diff)
+	}
+}
+
+func TestDiffTabloidVersions_FieldsAndPages(t *testing.T) {
+	from := interfaces.TabloidVersion{
+		Version:          1,
+		Nome:             "Tabloide Marcos",
+		RegiaoID:         144,
+		DtInicioVigencia: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+		DtFimVigencia:    time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC),
+		Pages:            []string{"page-1.png", "page-2.png"},
+	}
+	to := from
+	to.Version = 2
+	to.DtFimVigencia = time.Date(2024, 4, 12, 0, 0, 0, 0, time.UTC)
+	to.Pages = []string{"page-1.png", "page-2-new.png", "page-3.png"}
+
+	diff := DiffTabloidVersions(from, to)
+	if diff.From != 1 || diff.To != 2 {
+		t.Errorf("DiffTabloidVersions returned versions %d..%d, expected 1..2", diff.From, diff.To)
+	}
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "dt_fim_vigencia" {
+		t.Errorf("DiffTabloidVersions returned fields %+v, expected only dt_fim_vigencia", diff.Fields)
+	}
+
+	expectedPages := []interfaces.VersionPageChange{
+		{Order: 1, From: "page-2.png", To: "page-2-new.png"},
+		{Order: 2, From: "", To: "page-3.png"},
+	}
+	if len(diff.Pages) != len(expectedPages) {
+		t.Fatalf("DiffTabloidVersions returned pages %+v, expected %+v", diff.Pages, expectedPages)
+	}
+	for i, expected := range expectedPages {
+		if diff.Pages[i] != expected {
+			t.Errorf("DiffTabloidVersions page %d = %+v, expected %+v", i, diff.Pages[i], expected)
+		}
+	}
+}
+
+func TestDiffTabloidVersions_RemovedPage(t *testing.T) {
+	from := interfaces.TabloidVersion{Version: 3, Pages: []string{"page-1.png", "page-2.png"}}
+	to := interfaces.TabloidVersion{Version: 4, Pages: []string{"page-1.png"}}
+
+	diff := DiffTabloidVersions(from, to)
+	if len(diff.Pages) != 1 || diff.Pages[0].Order != 1 || diff.Pages[0].To != "" {
+		t.Errorf("DiffTabloidVersions returned pages %+v, expected page 1 removed", diff.Pages)
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"test/lambda/interfaces"
+)
+
+// NewDomainEvent builds a domain event about a tabloid, with the tabloid and the keys of its pages as payload.
+// The deduplication ID is derived from the type, the tabloid and discriminator, which tells apart events of the
+// same type about the same tabloid (a version number, a date) and is empty for events that happen once.
+//
+// Example:
+//
+//	event, err := NewDomainEvent(interfaces.EventPagesChanged, tabloid, pageKeys, "v3")
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	fmt.Println(event.DeduplicationID) // tabloide-42-PagesChanged-v3
+func NewDomainEvent(eventType string, tabloid interfaces.Tabloid, pages []string, discriminator string) (interfaces.DomainEvent, error) {
+	payload, err := json.Marshal(interfaces.EventPayload{Tabloid: tabloid, Pages: pages})
+	if err != nil {
+		return interfaces.DomainEvent{}, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	deduplicationID := fmt.Sprintf("tabloide-%d-%s", tabloid.ID, eventType)
+	if discriminator != "" {
+		deduplicationID += "-" + discriminator
+	}
+	return interfaces.DomainEvent{
+		DeduplicationID: deduplicationID,
+		Type:            eventType,
+		TabloidID:       tabloid.ID,
+		RegionID:        tabloid.RegiaoID,
+		Payload:         payload,
+	}, nil
+}
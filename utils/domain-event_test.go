@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/json"
+	"test/lambda/interfaces"
+	"testing"
+)
+
+func TestNewDomainEvent(t *testing.T) {
+	tabloid := interfaces.Tabloid{ID: 42, Nome: "Ofertas", RegiaoID: 144}
+
+	event, err := NewDomainEvent(interfaces.EventPagesChanged, tabloid, []string{"RPA/v3/42/pagina-1.png"}, "v3")
+	if err != nil {
+		t.Fatalf("NewDomainEvent returned error: %v", err)
+	}
+	if event.DeduplicationID != "tabloide-42-PagesChanged-v3" || event.TabloidID != 42 || event.RegionID != 144 {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	var payload interfaces.EventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.Tabloid.Nome != "Ofertas" || len(payload.Pages) != 1 {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	created, _ := NewDomainEvent(interfaces.EventTabloidCreated, tabloid, nil, "")
+	if created.DeduplicationID != "tabloide-42-TabloidCreated" {
+		t.Errorf("DeduplicationID = %q", created.DeduplicationID)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+)
+
+// GetEnvInt reads an integer environment variable.
+// It returns defaultValue if the variable is not set or is not a valid integer.
+//
+// Example:
+//
+//	maxAge := GetEnvInt("FEED_CACHE_MAX_AGE", 300)
+func GetEnvInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvFloat reads a floating-point environment variable.
+// It returns defaultValue if the variable is not set or is not a valid number.
+//
+// Example:
+//
+//	maxAspectRatio := GetEnvFloat("IMAGE_MAX_ASPECT_RATIO", 5)
+func GetEnvFloat(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Errors returned by ExpandZipPages.
+var (
+	ErrZipInvalid          = errors.New("invalid zip archive")
+	ErrZipTooManyEntries   = errors.New("zip archive has too many entries")
+	ErrZipEntryTooLarge    = errors.New("zip entry exceeds the maximum uncompressed size")
+	ErrZipTooLarge         = errors.New("zip archive exceeds the maximum total uncompressed size")
+	ErrZipCompressionRatio = errors.New("zip entry exceeds the maximum compression ratio")
+	ErrZipUnsafePath       = errors.New("zip entry has an unsafe path")
+	ErrZipEmpty            = errors.New("zip archive has no pages")
+)
+
+// ZipLimits bounds the resources used to expand a ZIP archive.
+type ZipLimits struct {
+	MaxEntries          int   // Maximum number of page entries.
+	MaxEntryBytes       int64 // Maximum uncompressed size of one entry.
+	MaxTotalBytes       int64 // Maximum uncompressed size of all entries together.
+	MaxCompressionRatio int64 // Maximum ratio between the uncompressed and the compressed size of one entry.
+}
+
+// ZipLimitsFromEnv reads the ZIP limits from the ZIP_MAX_ENTRIES, ZIP_MAX_ENTRY_BYTES,
+// ZIP_MAX_TOTAL_BYTES and ZIP_MAX_COMPRESSION_RATIO environment variables.
+func ZipLimitsFromEnv() ZipLimits {
+	return ZipLimits{
+		MaxEntries:          GetEnvInt("ZIP_MAX_ENTRIES", 50),
+		MaxEntryBytes:       int64(GetEnvInt("ZIP_MAX_ENTRY_BYTES", 20<<20)),
+		MaxTotalBytes:       int64(GetEnvInt("ZIP_MAX_TOTAL_BYTES", 100<<20)),
+		MaxCompressionRatio: int64(GetEnvInt("ZIP_MAX_COMPRESSION_RATIO", 100)),
+	}
+}
+
+// ZipPage is one page image expanded from a ZIP archive.
+type ZipPage struct {
+	Name string // Path of the entry inside the archive.
+	Data []byte // Uncompressed content of the entry.
+}
+
+// IsZip reports whether the content is a ZIP archive.
+func IsZip(content []byte) bool {
+	return bytes.HasPrefix(content, []byte("PK\x03\x04"))
+}
+
+// ExpandZipPages expands the entries of a ZIP archive into pages, in natural filename order.
+// Directories and metadata entries (__MACOSX, dotfiles) are skipped; every other entry must pass validate.
+// Sizes are enforced on the bytes actually decompressed, not on the sizes declared by the archive,
+// and entries with absolute paths or ".." segments are rejected.
+//
+// Example:
+//
+//	pages, err := ExpandZipPages(content, ZipLimitsFromEnv(), uploadService.ValidateImage)
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	for order, page := range pages {
+//	    fmt.Println(order, page.Name, len(page.Data))
+//	}
+func ExpandZipPages(content []byte, limits ZipLimits, validate func([]byte) error) ([]ZipPage, error) {
+	return ExpandZipPagesAt(bytes.NewReader(content), int64(len(content)), limits, validate)
+}
+
+// ExpandZipPagesAt expands the entries of a ZIP archive of size bytes read from archive, such as its temporary
+// file, as ExpandZipPages does. Only the pages are held in memory, not the archive.
+func ExpandZipPagesAt(archive io.ReaderAt, size int64, limits ZipLimits, validate func([]byte) error) ([]ZipPage, error) {
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrZipInvalid, err)
+	}
+
+	var entries []*zip.File
+	for _, entry := range reader.File {
+		if err := checkZipEntryPath(entry.Name); err != nil {
+			return nil, err
+		}
+		if entry.FileInfo().IsDir() || isZipMetadataEntry(entry.Name) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > limits.MaxEntries {
+			return nil, ErrZipTooManyEntries
+		}
+	}
+	if len(entries) == 0 {
+		return nil, ErrZipEmpty
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return NaturalLess(entries[i].Name, entries[j].Name)
+	})
+
+	var total int64
+	pages := make([]ZipPage, 0, len(entries))
+	for _, entry := range entries {
+		if entry.UncompressedSize64 > uint64(limits.MaxEntryBytes) {
+			return nil, fmt.Errorf("%w: %s", ErrZipEntryTooLarge, entry.Name)
+		}
+
+		data, err := readZipEntry(entry, limits.MaxEntryBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		compressed := int64(entry.CompressedSize64)
+		if compressed < 1 {
+			compressed = 1
+		}
+		if int64(len(data))/compressed > limits.MaxCompressionRatio {
+			return nil, fmt.Errorf("%w: %s", ErrZipCompressionRatio, entry.Name)
+		}
+
+		total += int64(len(data))
+		if total > limits.MaxTotalBytes {
+			return nil, ErrZipTooLarge
+		}
+
+		if err := validate(data); err != nil {
+			return nil, fmt.Errorf("invalid page %s: %w", entry.Name, err)
+		}
+		pages = append(pages, ZipPage{Name: entry.Name, Data: data})
+	}
+
+	return pages, nil
+}
+
+// readZipEntry decompresses an entry, failing as soon as it produces more than maxBytes.
+func readZipEntry(entry *zip.File, maxBytes int64) ([]byte, error) {
+	file, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrZipInvalid, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrZipInvalid, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: %s", ErrZipEntryTooLarge, entry.Name)
+	}
+	return data, nil
+}
+
+// checkZipEntryPath rejects entry names that would escape the archive root if extracted.
+func checkZipEntryPath(name string) error {
+	if strings.Contains(name, `\`) || strings.HasPrefix(name, "/") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w: %s", ErrZipUnsafePath, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return fmt.Errorf("%w: %s", ErrZipUnsafePath, name)
+		}
+	}
+	return nil
+}
+
+// isZipMetadataEntry reports whether the entry is metadata added by archivers, such as macOS resource forks.
+func isZipMetadataEntry(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".")
+}
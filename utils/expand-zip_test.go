@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testZipLimits = ZipLimits{MaxEntries: 5, MaxEntryBytes: 1 << 10, MaxTotalBytes: 2 << 10, MaxCompressionRatio: 10}
+
+func buildZip(t *testing.T, entries map[string][]byte, order []string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range order {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		file.Write(entries[name])
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func acceptAll([]byte) error { return nil }
+
+func TestExpandZipPages_NaturalOrder(t *testing.T) {
+	names := []string{"pagina-10.png", "pagina-2.png", "__MACOSX/._pagina-1.png", "pagina-1.png", ".DS_Store"}
+	entries := map[string][]byte{}
+	for _, name := range names {
+		entries[name] = []byte(name)
+	}
+
+	pages, err := ExpandZipPages(buildZip(t, entries, names), testZipLimits, acceptAll)
+	if err != nil {
+		t.Fatalf("ExpandZipPages returned an error: %v", err)
+	}
+
+	expected := []string{"pagina-1.png", "pagina-2.png", "pagina-10.png"}
+	if len(pages) != len(expected) {
+		t.Fatalf("ExpandZipPages returned %d pages, expected %d", len(pages), len(expected))
+	}
+	for i, name := range expected {
+		if pages[i].Name != name || string(pages[i].Data) != name {
+			t.Errorf("ExpandZipPages page %d = %s, expected %s", i, pages[i].Name, name)
+		}
+	}
+}
+
+func TestExpandZipPages_Limits(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  map[string][]byte
+		order    []string
+		expected error
+	}{
+		{"path traversal", map[string][]byte{"../evil.png": {1}}, []string{"../evil.png"}, ErrZipUnsafePath},
+		{"absolute path", map[string][]byte{"/etc/evil.png": {1}}, []string{"/etc/evil.png"}, ErrZipUnsafePath},
+		{"too many entries", map[string][]byte{"1": {1}, "2": {1}, "3": {1}, "4": {1}, "5": {1}, "6": {1}},
+			[]string{"1", "2", "3", "4", "5", "6"}, ErrZipTooManyEntries},
+		{"entry too large", map[string][]byte{"big.png": bytes.Repeat([]byte{7, 3, 1}, 1<<9)}, []string{"big.png"}, ErrZipEntryTooLarge},
+		{"compression bomb", map[string][]byte{"bomb.png": make([]byte, 1<<10)}, []string{"bomb.png"}, ErrZipCompressionRatio},
+		{"empty", map[string][]byte{"folder/": nil}, []string{"folder/"}, ErrZipEmpty},
+	}
+
+	for _, test := range tests {
+		_, err := ExpandZipPages(buildZip(t, test.entries, test.order), testZipLimits, acceptAll)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: ExpandZipPages returned %v, expected %v", test.name, err, test.expected)
+		}
+	}
+}
+
+func TestExpandZipPages_Validation(t *testing.T) {
+	invalid := errors.New("invalid image type")
+	content := buildZip(t, map[string][]byte{"pagina-1.txt": []byte("text")}, []string{"pagina-1.txt"})
+
+	_, err := ExpandZipPages(content, testZipLimits, func([]byte) error { return invalid })
+	if !errors.Is(err, invalid) {
+		t.Errorf("ExpandZipPages returned %v, expected the validation error", err)
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"pagina-2.png", "pagina-10.png", true},
+		{"pagina-10.png", "pagina-2.png", false},
+		{"Pagina-1.png", "pagina-2.png", true},
+		{"pagina-02.png", "pagina-3.png", true},
+		{"a", "ab", true},
+	}
+	for _, test := range tests {
+		if less := NaturalLess(test.a, test.b); less != test.expected {
+			t.Errorf("NaturalLess(%q, %q) returned %v, expected %v", test.a, test.b, less, test.expected)
+		}
+	}
+}
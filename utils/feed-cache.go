@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// ValidityEnd returns the instant a validity end date stops being valid.
+// Dates without a time of day (as written by the create endpoint) are valid for the whole day.
+//
+// Example:
+//
+//	end := ValidityEnd(time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC))
+//	fmt.Println(end) // 2024-04-11 00:00:00 +0000 UTC
+func ValidityEnd(endValidityDate time.Time) time.Time {
+	if endValidityDate.Equal(endValidityDate.Truncate(24 * time.Hour)) {
+		return endValidityDate.AddDate(0, 0, 1)
+	}
+	return endValidityDate
+}
+
+// IsTabloidCurrent reports whether the tabloid is active and valid at the given instant.
+func IsTabloidCurrent(tabloid interfaces.Tabloid, now time.Time) bool {
+	return tabloid.Ativo && !now.Before(tabloid.DtInicioVigencia) && now.Before(ValidityEnd(tabloid.DtFimVigencia))
+}
+
+// NextValidityBoundary returns the nearest instant after now at which one of the tabloids
+// starts or stops being valid. It returns false if no boundary lies in the future.
+//
+// Example:
+//
+//	boundary, ok := NextValidityBoundary(tabloids, time.Now())
+//	if ok {
+//	    fmt.Println("Feed content changes at", boundary)
+//	}
+func NextValidityBoundary(tabloids []interfaces.Tabloid, now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, tabloid := range tabloids {
+		for _, boundary := range []time.Time{tabloid.DtInicioVigencia, ValidityEnd(tabloid.DtFimVigencia)} {
+			if boundary.After(now) && (!found || boundary.Before(next)) {
+				next = boundary
+				found = true
+			}
+		}
+	}
+	return next, found
+}
+
+// CacheControlMaxAge returns how many seconds a feed may be cached: maxAge, shortened so
+// the cached copy does not outlive the next validity boundary.
+func CacheControlMaxAge(now, boundary time.Time, hasBoundary bool, maxAge int) int {
+	if !hasBoundary {
+		return maxAge
+	}
+	untilBoundary := int(boundary.Sub(now) / time.Second)
+	if untilBoundary < maxAge {
+		return untilBoundary
+	}
+	return maxAge
+}
+
+// ComputeETag computes a strong ETag from the response body and the last modification date of its content.
+//
+// Example:
+//
+//	etag := ComputeETag(body, lastModified)
+//	c.Header("ETag", etag) // "3f1c...e9"
+func ComputeETag(body []byte, lastModified time.Time) string {
+	hash := sha256.New()
+	hash.Write(body)
+	hash.Write([]byte(lastModified.UTC().Format(time.RFC3339Nano)))
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// ETagMatches reports whether an If-None-Match header value matches the ETag.
+// It accepts "*" and comma-separated lists, comparing weakly as RFC 7232 requires for If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
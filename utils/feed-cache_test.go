@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"test/lambda/interfaces"
+	"testing"
+	"time"
+)
+
+func TestIsTabloidCurrent(t *testing.T) {
+	tabloid := interfaces.Tabloid{
+		Ativo:            true,
+		DtInicioVigencia: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+		DtFimVigencia:    time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		now      time.Time
+		expected bool
+	}{
+		{time.Date(2024, 4, 7, 23, 59, 0, 0, time.UTC), false},
+		{time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 4, 10, 18, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if current := IsTabloidCurrent(tabloid, test.now); current != test.expected {
+			t.Errorf("IsTabloidCurrent(%v) returned %v, expected %v", test.now, current, test.expected)
+		}
+	}
+
+	tabloid.Ativo = false
+	if IsTabloidCurrent(tabloid, time.Date(2024, 4, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("IsTabloidCurrent returned true for an inactive tabloid")
+	}
+}
+
+func TestNextValidityBoundary(t *testing.T) {
+	now := time.Date(2024, 4, 9, 12, 0, 0, 0, time.UTC)
+	tabloids := []interfaces.Tabloid{
+		{DtInicioVigencia: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC), DtFimVigencia: time.Date(2024, 4, 12, 0, 0, 0, 0, time.UTC)},
+		{DtInicioVigencia: time.Date(2024, 4, 10, 6, 0, 0, 0, time.UTC), DtFimVigencia: time.Date(2024, 4, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	boundary, ok := NextValidityBoundary(tabloids, now)
+	expected := time.Date(2024, 4, 10, 6, 0, 0, 0, time.UTC)
+	if !ok || !boundary.Equal(expected) {
+		t.Errorf("NextValidityBoundary returned %v, %v, expected %v", boundary, ok, expected)
+	}
+
+	if _, ok := NextValidityBoundary(nil, now); ok {
+		t.Errorf("NextValidityBoundary returned a boundary for no tabloids")
+	}
+}
+
+func TestCacheControlMaxAge(t *testing.T) {
+	now := time.Date(2024, 4, 9, 12, 0, 0, 0, time.UTC)
+	if maxAge := CacheControlMaxAge(now, now.Add(90*time.Second), true, 300); maxAge != 90 {
+		t.Errorf("CacheControlMaxAge returned %d, expected 90", maxAge)
+	}
+	if maxAge := CacheControlMaxAge(now, now.Add(time.Hour), true, 300); maxAge != 300 {
+		t.Errorf("CacheControlMaxAge returned %d, expected 300", maxAge)
+	}
+	if maxAge := CacheControlMaxAge(now, time.Time{}, false, 300); maxAge != 300 {
+		t.Errorf("CacheControlMaxAge returned %d, expected 300", maxAge)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	lastModified := time.Date(2024, 4, 9, 12, 0, 0, 0, time.UTC)
+	etag := ComputeETag([]byte(`{"regiao_id":1}`), lastModified)
+
+	if etag != ComputeETag([]byte(`{"regiao_id":1}`), lastModified) {
+		t.Errorf("ComputeETag is not deterministic")
+	}
+	if etag == ComputeETag([]byte(`{"regiao_id":1}`), lastModified.Add(time.Second)) {
+		t.Errorf("ComputeETag ignored the last modification date")
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{etag, true},
+		{"W/" + etag, true},
+		{`"other", ` + etag, true},
+		{"*", true},
+		{`"other"`, false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if matches := ETagMatches(test.ifNoneMatch, etag); matches != test.expected {
+			t.Errorf("ETagMatches(%q) returned %v, expected %v", test.ifNoneMatch, matches, test.expected)
+		}
+	}
+}
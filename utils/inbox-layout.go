@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// InboxManifestName is the name of the manifest a tabloid dropped in the S3 inbox is described by.
+const InboxManifestName = "manifest.json"
+
+// InboxLayout locates the files of the S3 inbox, where tabloids are dropped as a folder holding a manifest.json
+// and the page files it lists, and where they are moved once processed.
+type InboxLayout struct {
+	Prefix       string // Prefix watched for manifests.
+	DonePrefix   string // Prefix the files of created tabloids are moved under.
+	FailedPrefix string // Prefix the files of failed tabloids are moved under, with an error report.
+}
+
+// InboxLayoutFromEnv reads the inbox layout from INBOX_PREFIX ("inbox/" by default), INBOX_DONE_PREFIX ("done/")
+// and INBOX_FAILED_PREFIX ("failed/").
+// It returns an error if the done or failed prefix is under the inbox prefix, as moved manifests would trigger again.
+func InboxLayoutFromEnv() (InboxLayout, error) {
+	layout := InboxLayout{
+		Prefix:       os.Getenv("INBOX_PREFIX"),
+		DonePrefix:   os.Getenv("INBOX_DONE_PREFIX"),
+		FailedPrefix: os.Getenv("INBOX_FAILED_PREFIX"),
+	}
+	if layout.Prefix == "" {
+		layout.Prefix = "inbox/"
+	}
+	if layout.DonePrefix == "" {
+		layout.DonePrefix = "done/"
+	}
+	if layout.FailedPrefix == "" {
+		layout.FailedPrefix = "failed/"
+	}
+
+	for _, prefix := range []string{layout.DonePrefix, layout.FailedPrefix} {
+		if strings.HasPrefix(prefix, layout.Prefix) {
+			return layout, fmt.Errorf("inbox prefix %s must not contain %s", layout.Prefix, prefix)
+		}
+	}
+	return layout, nil
+}
+
+// IsManifest reports whether key is the manifest of a tabloid dropped in the inbox.
+func (l InboxLayout) IsManifest(key string) bool {
+	return strings.HasPrefix(key, l.Prefix) && path.Base(key) == InboxManifestName
+}
+
+// FileKey returns the key of a file listed by the manifest stored under manifestKey: names are relative
+// to the folder of the manifest.
+// It returns an error for absolute names and names leaving that folder.
+func (l InboxLayout) FileKey(manifestKey, name string) (string, error) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(name) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid file name in manifest: %q", name)
+	}
+	return path.Join(path.Dir(manifestKey), cleaned), nil
+}
+
+// DoneKey returns the key a file of the inbox is moved to once its tabloid is created.
+func (l InboxLayout) DoneKey(key string) string {
+	return l.DonePrefix + strings.TrimPrefix(key, l.Prefix)
+}
+
+// FailedKey returns the key a file of the inbox is moved to when its tabloid cannot be created.
+func (l InboxLayout) FailedKey(key string) string {
+	return l.FailedPrefix + strings.TrimPrefix(key, l.Prefix)
+}
+
+// ErrorReportKey returns the key of the error report written next to the failed files of the manifest
+// stored under manifestKey.
+func (l InboxLayout) ErrorReportKey(manifestKey string) string {
+	return path.Join(path.Dir(l.FailedKey(manifestKey)), "error.json")
+}
@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+func TestInboxLayout(t *testing.T) {
+	layout := InboxLayout{Prefix: "inbox/", DonePrefix: "done/", FailedPrefix: "failed/"}
+	manifest := "inbox/2024-04-08/ofertas/manifest.json"
+
+	if !layout.IsManifest(manifest) {
+		t.Errorf("IsManifest(%s) = false", manifest)
+	}
+	for _, key := range []string{"inbox/2024-04-08/ofertas/pagina-1.png", "done/2024-04-08/ofertas/manifest.json"} {
+		if layout.IsManifest(key) {
+			t.Errorf("IsManifest(%s) = true", key)
+		}
+	}
+
+	key, err := layout.FileKey(manifest, "paginas/pagina-1.png")
+	if err != nil || key != "inbox/2024-04-08/ofertas/paginas/pagina-1.png" {
+		t.Errorf("FileKey = %q, %v", key, err)
+	}
+	for _, name := range []string{"../outro/pagina-1.png", "/etc/passwd", ".", "paginas/../../x.png"} {
+		if _, err := layout.FileKey(manifest, name); err == nil {
+			t.Errorf("FileKey(%q) returned no error", name)
+		}
+	}
+
+	if got := layout.DoneKey(key); got != "done/2024-04-08/ofertas/paginas/pagina-1.png" {
+		t.Errorf("DoneKey = %q", got)
+	}
+	if got := layout.FailedKey(manifest); got != "failed/2024-04-08/ofertas/manifest.json" {
+		t.Errorf("FailedKey = %q", got)
+	}
+	if got := layout.ErrorReportKey(manifest); got != "failed/2024-04-08/ofertas/error.json" {
+		t.Errorf("ErrorReportKey = %q", got)
+	}
+}
+
+func TestInboxLayoutFromEnv(t *testing.T) {
+	t.Setenv("INBOX_PREFIX", "")
+	t.Setenv("INBOX_DONE_PREFIX", "")
+	t.Setenv("INBOX_FAILED_PREFIX", "")
+	layout, err := InboxLayoutFromEnv()
+	if err != nil || layout.Prefix != "inbox/" || layout.DonePrefix != "done/" || layout.FailedPrefix != "failed/" {
+		t.Errorf("InboxLayoutFromEnv = %+v, %v", layout, err)
+	}
+
+	t.Setenv("INBOX_DONE_PREFIX", "inbox/done/")
+	if _, err := InboxLayoutFromEnv(); err == nil {
+		t.Error("InboxLayoutFromEnv accepted a done prefix under the inbox")
+	}
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default key templates, matching the layouts used before templates were configurable.
+const (
+	DefaultKeyTemplate       = "RPA/v3/{tabloid_id}/campanha-{tabloid_id}-{uuid}-pagina-{page}{ext}"
+	DefaultSHA256KeyTemplate = "RPA/v3/sha256/{sha256}{ext}"
+)
+
+// KeyFields holds the values the placeholders of a KeyTemplate are replaced with.
+type KeyFields struct {
+	TabloidID int64     // {tabloid_id}
+	RegionID  int       // {region_id}
+	StartDate time.Time // {start_date}, as YYYY-MM-DD
+	EndDate   time.Time // {end_date}, as YYYY-MM-DD
+	Page      int       // {page}, one-based
+	SHA256    string    // {sha256}, hex-encoded hash of the stored content
+	UUID      string    // {uuid}
+	Extension string    // {ext}, with the leading dot
+}
+
+// keyPlaceholders maps each placeholder to its value and to the pattern matching any value it can take.
+var keyPlaceholders = map[string]struct {
+	value   func(KeyFields) string
+	pattern string
+}{
+	"tabloid_id": {func(f KeyFields) string { return strconv.FormatInt(f.TabloidID, 10) }, `[0-9]+`},
+	"region_id":  {func(f KeyFields) string { return strconv.Itoa(f.RegionID) }, `[0-9]+`},
+	"start_date": {func(f KeyFields) string { return f.StartDate.Format("2006-01-02") }, `[0-9]{4}-[0-9]{2}-[0-9]{2}`},
+	"end_date":   {func(f KeyFields) string { return f.EndDate.Format("2006-01-02") }, `[0-9]{4}-[0-9]{2}-[0-9]{2}`},
+	"page":       {func(f KeyFields) string { return strconv.Itoa(f.Page) }, `[0-9]+`},
+	"sha256":     {func(f KeyFields) string { return f.SHA256 }, `[0-9a-f]{64}`},
+	"uuid":       {func(f KeyFields) string { return f.UUID }, `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`},
+	"ext":        {func(f KeyFields) string { return f.Extension }, `\.[a-z0-9]+`},
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// KeyTemplate is a validated layout of S3 keys, such as "RPA/v3/{region_id}/{tabloid_id}/{uuid}-pagina-{page}{ext}".
+type KeyTemplate struct {
+	template     string
+	placeholders map[string]bool
+}
+
+// ParseKeyTemplate validates a key template. Templates must only use known placeholders, end with {ext},
+// and contain {uuid} or {sha256} so two different images never share a key. When contentAddressed is set
+// the key must identify the content alone, so only {sha256} and {ext} are allowed.
+//
+// Example:
+//
+//	template, err := ParseKeyTemplate("RPA/v3/{region_id}/{tabloid_id}/{uuid}-pagina-{page}{ext}", false)
+//	if err != nil {
+//	    log.Fatalf("Invalid IMAGE_KEY_TEMPLATE: %v", err)
+//	}
+func ParseKeyTemplate(template string, contentAddressed bool) (*KeyTemplate, error) {
+	if template == "" {
+		return nil, errors.New("empty key template")
+	}
+	if strings.HasPrefix(template, "/") || strings.Contains(template, "..") || strings.Contains(template, "//") {
+		return nil, fmt.Errorf("key template %q must be a relative path without empty or parent segments", template)
+	}
+
+	rest := placeholderPattern.ReplaceAllString(template, "")
+	if strings.ContainsAny(rest, "{}") {
+		return nil, fmt.Errorf("key template %q has unbalanced braces", template)
+	}
+
+	placeholders := map[string]bool{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if _, ok := keyPlaceholders[name]; !ok {
+			return nil, fmt.Errorf("unknown placeholder {%s} in key template", name)
+		}
+		if contentAddressed && name != "sha256" && name != "ext" {
+			return nil, fmt.Errorf("content-addressed key template cannot use {%s}", name)
+		}
+		placeholders[name] = true
+	}
+
+	if !strings.HasSuffix(template, "{ext}") {
+		return nil, fmt.Errorf("key template %q must end with {ext}", template)
+	}
+	if !placeholders["uuid"] && !placeholders["sha256"] {
+		return nil, fmt.Errorf("key template %q must contain {uuid} or {sha256}", template)
+	}
+	if contentAddressed && !placeholders["sha256"] {
+		return nil, fmt.Errorf("content-addressed key template %q must contain {sha256}", template)
+	}
+
+	return &KeyTemplate{template: template, placeholders: placeholders}, nil
+}
+
+// Uses reports whether the template contains the given placeholder, without braces.
+func (t *KeyTemplate) Uses(placeholder string) bool {
+	return t.placeholders[placeholder]
+}
+
+// String returns the template.
+func (t *KeyTemplate) String() string {
+	return t.template
+}
+
+// Render replaces the placeholders of the template with the given fields.
+//
+// Example:
+//
+//	key := template.Render(KeyFields{TabloidID: 42, RegionID: 7, Page: 1, UUID: uuid.NewString(), Extension: ".png"})
+//	fmt.Println(key) // RPA/v3/7/42/3f1c...-pagina-1.png
+func (t *KeyTemplate) Render(fields KeyFields) string {
+	return placeholderPattern.ReplaceAllStringFunc(t.template, func(placeholder string) string {
+		return keyPlaceholders[placeholder[1:len(placeholder)-1]].value(fields)
+	})
+}
+
+// Matches reports whether key follows the template for the given fields. {uuid} matches any UUID,
+// {sha256} matches any hash when fields.SHA256 is empty, and {ext} matches any extension.
+func (t *KeyTemplate) Matches(key string, fields KeyFields) bool {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(t.template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(t.template[last:match[0]]))
+		name := t.template[match[2]:match[3]]
+		switch {
+		case name == "uuid" || name == "ext" || (name == "sha256" && fields.SHA256 == ""):
+			pattern.WriteString(keyPlaceholders[name].pattern)
+		default:
+			pattern.WriteString(regexp.QuoteMeta(keyPlaceholders[name].value(fields)))
+		}
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(t.template[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String()).MatchString(key)
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKeyTemplate(t *testing.T) {
+	tests := []struct {
+		template         string
+		contentAddressed bool
+		valid            bool
+	}{
+		{DefaultKeyTemplate, false, true},
+		{DefaultSHA256KeyTemplate, true, true},
+		{"RPA/v3/{region_id}/{start_date}/{tabloid_id}/{sha256}{ext}", false, true},
+		{"", false, false},
+		{"/RPA/{uuid}{ext}", false, false},
+		{"RPA/../{uuid}{ext}", false, false},
+		{"RPA/{tabloid}/{uuid}{ext}", false, false},
+		{"RPA/{uuid{ext}", false, false},
+		{"RPA/{uuid}{ext}.png", false, false},
+		{"RPA/{tabloid_id}-pagina-{page}{ext}", false, false},
+		{"RPA/{tabloid_id}/{sha256}{ext}", true, false},
+		{"RPA/{uuid}{ext}", true, false},
+	}
+	for _, test := range tests {
+		_, err := ParseKeyTemplate(test.template, test.contentAddressed)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseKeyTemplate(%q, %v) returned error %v, expected valid=%v", test.template, test.contentAddressed, err, test.valid)
+		}
+	}
+}
+
+func TestKeyTemplateRender(t *testing.T) {
+	fields := KeyFields{
+		TabloidID: 42,
+		RegionID:  7,
+		StartDate: time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC),
+		Page:      3,
+		SHA256:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		UUID:      "123e4567-e89b-12d3-a456-426614174000",
+		Extension: ".png",
+	}
+
+	template, err := ParseKeyTemplate(DefaultKeyTemplate, false)
+	if err != nil {
+		t.Fatalf("ParseKeyTemplate returned error: %v", err)
+	}
+	expected := "RPA/v3/42/campanha-42-123e4567-e89b-12d3-a456-426614174000-pagina-3.png"
+	if key := template.Render(fields); key != expected {
+		t.Errorf("Render returned %q, expected %q", key, expected)
+	}
+
+	template, err = ParseKeyTemplate("{region_id}/{start_date}_{end_date}/{tabloid_id}/{page}-{sha256}{ext}", false)
+	if err != nil {
+		t.Fatalf("ParseKeyTemplate returned error: %v", err)
+	}
+	expected = "7/2024-04-08_2024-04-14/42/3-9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+	if key := template.Render(fields); key != expected {
+		t.Errorf("Render returned %q, expected %q", key, expected)
+	}
+}
+
+func TestKeyTemplateMatches(t *testing.T) {
+	template, err := ParseKeyTemplate(DefaultKeyTemplate, false)
+	if err != nil {
+		t.Fatalf("ParseKeyTemplate returned error: %v", err)
+	}
+	fields := KeyFields{TabloidID: 42, Page: 3}
+
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{"RPA/v3/42/campanha-42-123e4567-e89b-12d3-a456-426614174000-pagina-3.png", true},
+		{"RPA/v3/42/campanha-42-123e4567-e89b-12d3-a456-426614174000-pagina-3.jpeg", true},
+		{"RPA/v3/42/campanha-42-123e4567-e89b-12d3-a456-426614174000-pagina-4.png", false},
+		{"RPA/v3/43/campanha-43-123e4567-e89b-12d3-a456-426614174000-pagina-3.png", false},
+		{"RPA/v3/sha256/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png", false},
+	}
+	for _, test := range tests {
+		if matches := template.Matches(test.key, fields); matches != test.expected {
+			t.Errorf("Matches(%q) returned %v, expected %v", test.key, matches, test.expected)
+		}
+	}
+}
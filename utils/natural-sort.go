@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NaturalLess reports whether a sorts before b in natural order: case-insensitive, with runs of
+// digits compared by their numeric value, so "pagina-2.png" sorts before "pagina-10.png".
+//
+// Example:
+//
+//	names := []string{"pagina-10.png", "pagina-2.png", "pagina-1.png"}
+//	sort.Slice(names, func(i, j int) bool { return NaturalLess(names[i], names[j]) })
+//	fmt.Println(names) // [pagina-1.png pagina-2.png pagina-10.png]
+func NaturalLess(a, b string) bool {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			startA, startB := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			numberA := strings.TrimLeft(string(ra[startA:i]), "0")
+			numberB := strings.TrimLeft(string(rb[startB:j]), "0")
+			if len(numberA) != len(numberB) {
+				return len(numberA) < len(numberB)
+			}
+			if numberA != numberB {
+				return numberA < numberB
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	_ "golang.org/x/image/webp" // Registers the WebP decoder with image.Decode.
+)
+
+// Canonical output formats accepted by NormalizeImage.
+const (
+	ImageFormatOriginal = ""
+	ImageFormatPNG      = "png"
+	ImageFormatJPEG     = "jpeg"
+)
+
+// IsAnimatedGif reports whether the content is a GIF with more than one frame.
+//
+// Example:
+//
+//	animated, err := IsAnimatedGif(content)
+//	if err == nil && animated {
+//	    fmt.Println("Only static GIFs are accepted")
+//	}
+func IsAnimatedGif(content []byte) (bool, error) {
+	decoded, err := gif.DecodeAll(bytes.NewReader(content))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode gif: %w", err)
+	}
+	return len(decoded.Image) > 1, nil
+}
+
+// NormalizeImage transcodes an image to the canonical output format ("png" or "jpeg").
+// The content is returned unchanged when outputFormat is empty or the image already has that format.
+// Transparent areas are flattened onto white when transcoding to JPEG, which has no alpha channel.
+//
+// Example:
+//
+//	normalized, err := NormalizeImage(webpContent, ImageFormatPNG)
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	fmt.Println(http.DetectContentType(normalized)) // image/png
+func NormalizeImage(content []byte, outputFormat string) ([]byte, error) {
+	outputFormat = strings.ToLower(strings.TrimSpace(outputFormat))
+	if outputFormat == "jpg" {
+		outputFormat = ImageFormatJPEG
+	}
+	if outputFormat == ImageFormatOriginal || http.DetectContentType(content) == "image/"+outputFormat {
+		return content, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	switch outputFormat {
+	case ImageFormatPNG:
+		err = png.Encode(&buffer, decoded)
+	case ImageFormatJPEG:
+		err = jpeg.Encode(&buffer, flattenImage(decoded), &jpeg.Options{Quality: 90})
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// flattenImage draws the image over a white background, discarding its alpha channel.
+func flattenImage(source image.Image) image.Image {
+	flattened := image.NewRGBA(source.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), source, source.Bounds().Min, draw.Over)
+	return flattened
+}
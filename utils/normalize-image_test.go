@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/gif"
+	"net/http"
+	"testing"
+)
+
+// webpPixel is a lossless 1x1 WebP image.
+const webpPixel = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func encodeTestGif(t *testing.T, frames int) []byte {
+	t.Helper()
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buffer bytes.Buffer
+	if err := gif.EncodeAll(&buffer, animation); err != nil {
+		t.Fatalf("failed to encode test gif: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestNormalizeImage_WebpToPNG(t *testing.T) {
+	content, _ := base64.StdEncoding.DecodeString(webpPixel)
+
+	normalized, err := NormalizeImage(content, ImageFormatPNG)
+	if err != nil {
+		t.Fatalf("NormalizeImage returned an error: %v", err)
+	}
+	if contentType := http.DetectContentType(normalized); contentType != "image/png" {
+		t.Errorf("NormalizeImage returned %s, expected image/png", contentType)
+	}
+}
+
+func TestNormalizeImage_GifToJPEG(t *testing.T) {
+	normalized, err := NormalizeImage(encodeTestGif(t, 1), "jpg")
+	if err != nil {
+		t.Fatalf("NormalizeImage returned an error: %v", err)
+	}
+	if contentType := http.DetectContentType(normalized); contentType != "image/jpeg" {
+		t.Errorf("NormalizeImage returned %s, expected image/jpeg", contentType)
+	}
+}
+
+func TestNormalizeImage_KeepOriginal(t *testing.T) {
+	content := encodeTestPNG(t, 2, 2)
+	for _, outputFormat := range []string{ImageFormatOriginal, ImageFormatPNG} {
+		normalized, err := NormalizeImage(content, outputFormat)
+		if err != nil || !bytes.Equal(normalized, content) {
+			t.Errorf("NormalizeImage(%q) changed the content (err %v)", outputFormat, err)
+		}
+	}
+}
+
+func TestIsAnimatedGif(t *testing.T) {
+	if animated, err := IsAnimatedGif(encodeTestGif(t, 1)); err != nil || animated {
+		t.Errorf("IsAnimatedGif returned %v, %v for a static gif", animated, err)
+	}
+	if animated, err := IsAnimatedGif(encodeTestGif(t, 3)); err != nil || !animated {
+		t.Errorf("IsAnimatedGif returned %v, %v for an animated gif", animated, err)
+	}
+}
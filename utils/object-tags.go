@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SanitizeTagValue makes a value safe for both S3 object tags and user metadata: characters outside the set
+// allowed in tags (letters, digits, spaces and + - = . _ : / @) are replaced with "_", and the value is cut
+// to the 256 characters a tag value may hold.
+//
+// Example:
+//
+//	fmt.Println(SanitizeTagValue("joão.silva")) // jo_o.silva
+func SanitizeTagValue(value string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune(" +-=._:/@", r):
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+	if len(sanitized) > 256 {
+		sanitized = sanitized[:256]
+	}
+	return sanitized
+}
+
+// EncodeObjectTags encodes tags as the URL query string S3 expects in the Tagging parameter of PutObject,
+// sanitizing every value.
+//
+// Example:
+//
+//	tagging := EncodeObjectTags(map[string]string{"tabloide-id": "42", "fim-vigencia": "2024-04-14"})
+//	fmt.Println(tagging) // fim-vigencia=2024-04-14&tabloide-id=42
+func EncodeObjectTags(tags map[string]string) string {
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, SanitizeTagValue(value))
+	}
+	return values.Encode()
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeTagValue(t *testing.T) {
+	tests := map[string]string{
+		"marcos":                 "marcos",
+		"joão.silva":             "jo_o.silva",
+		"ana+ops@example.com":    "ana+ops@example.com",
+		"a,b;c":                  "a_b_c",
+		"2024-04-14":             "2024-04-14",
+		strings.Repeat("x", 300): strings.Repeat("x", 256),
+	}
+	for value, expected := range tests {
+		if sanitized := SanitizeTagValue(value); sanitized != expected {
+			t.Errorf("SanitizeTagValue(%q) returned %q, expected %q", value, sanitized, expected)
+		}
+	}
+}
+
+func TestEncodeObjectTags(t *testing.T) {
+	tagging := EncodeObjectTags(map[string]string{"usuario": "joão silva", "tabloide-id": "42"})
+	expected := "tabloide-id=42&usuario=jo_o+silva"
+	if tagging != expected {
+		t.Errorf("EncodeObjectTags returned %q, expected %q", tagging, expected)
+	}
+}
@@ -1,61 +1,113 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
 	"test/lambda/interfaces"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ParseFormData parses form data from the given Gin context.
-// It returns a RequestEvent or an error if parsing fails.
+// maxFieldBytes bounds the size of the non-file fields of the form.
+const maxFieldBytes = 64 << 10
+
+// ParseFormData parses the multipart form of an upload request from the given Gin context.
+// The parts are read as they arrive, without ParseMultipartForm: the request body is bounded by
+// limits.MaxRequestBytes and the file is read once, with its first bytes checked before the rest (see readFilePart).
+// A ZIP archive is kept in a temporary file, which the caller removes with RemoveSpooledFiles once the request is
+// processed; it is removed here when parsing fails.
+// It returns a RequestEvent, an *UploadTooLargeError when a limit is exceeded, or an error if parsing fails or the
+// form holds more than one file.
 //
 // Example:
 //
-//	c.Request.Form.Set("name", "Tabloide Marcos")
-//	c.Request.Form.Set("region_id", "144")
-//	c.Request.Form.Set("start_validity_date", "2024-04-08")
-//	c.Request.Form.Set("end_validity_date", "2024-04-10")
-//	file, _ := c.FormFile("file") // Assume you have a file uploaded in the form
-//	event, err := ParseFormData(c)
+//	// multipart form with name, region_id, start_validity_date, end_validity_date and file
+//	event, err := ParseFormData(c, UploadLimitsFromEnv())
 //	if err != nil {
 //	    fmt.Println("Error:", err)
 //	    return
 //	}
 //	fmt.Println("Parsed form data:", event)
-func ParseFormData(c *gin.Context) (*interfaces.RequestEvent, error) {
-	event := &interfaces.RequestEvent{
-		Name: c.Request.FormValue("name"),
+func ParseFormData(c *gin.Context, limits UploadLimits) (_ *interfaces.RequestEvent, err error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxRequestBytes)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart form: %w", err)
 	}
 
-	regionID, err := strconv.Atoi(c.Request.FormValue("region_id"))
+	event := &interfaces.RequestEvent{}
+	defer func() {
+		if err != nil {
+			RemoveSpooledFiles(event.File)
+		}
+	}()
+	fields := map[string]string{}
+	hasFile := false
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, requestReadError(err, limits)
+		}
+
+		switch {
+		case part.FormName() == "file":
+			// A second file would replace the first, leaving its temporary file behind
+			if hasFile {
+				return nil, errors.New("failed to get file: more than one file in the form")
+			}
+			event.File, err = readFilePart(part, limits)
+			if err != nil {
+				return nil, requestReadError(err, limits)
+			}
+			hasFile = true
+		case part.FileName() == "":
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes))
+			if err != nil {
+				return nil, requestReadError(err, limits)
+			}
+			fields[part.FormName()] = string(value)
+		}
+		part.Close()
+	}
+
+	event.Name = fields["name"]
+
+	regionID, err := strconv.Atoi(fields["region_id"])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse region_id: %w", err)
 	}
 	event.RegionID = regionID
 
-	startValidityDate, err := parseDate(c.Request.FormValue("start_validity_date"))
+	startValidityDate, err := parseDate(fields["start_validity_date"])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse start_validity_date: %w", err)
 	}
 	event.StartValidityDate = startValidityDate
 
-	endValidityDate, err := parseDate(c.Request.FormValue("end_validity_date"))
+	endValidityDate, err := parseDate(fields["end_validity_date"])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse end_validity_date: %w", err)
 	}
 	event.EndValidityDate = endValidityDate
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file: %w", err)
-	}
-
-	event.File, err = parseFile(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+	if !hasFile {
+		return nil, errors.New("failed to get file: no file in the form")
 	}
 
 	return event, nil
 }
+
+// requestReadError turns the error of a body read beyond MaxRequestBytes into an *UploadTooLargeError.
+func requestReadError(err error, limits UploadLimits) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return &UploadTooLargeError{Limit: limits.MaxRequestBytes, What: "request"}
+	}
+	return err
+}
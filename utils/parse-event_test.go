@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func uploadContext(t *testing.T, contentType string, files ...[]byte) *gin.Context {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range map[string]string{
+		"name":                "Tabloide Marcos",
+		"region_id":           "144",
+		"start_validity_date": "2024-04-08",
+		"end_validity_date":   "2024-04-10",
+	} {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("WriteField returned error: %v", err)
+		}
+	}
+	for _, file := range files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="pagina.png"`)
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			t.Fatalf("CreatePart returned error: %v", err)
+		}
+		part.Write(file)
+	}
+	writer.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/test", &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+func TestParseFormData(t *testing.T) {
+	content := encodeTestPNG(t, 40, 20)
+	limits := UploadLimits{MaxPageBytes: 1 << 20, MaxRequestBytes: 2 << 20}
+
+	event, err := ParseFormData(uploadContext(t, "image/png", content), limits)
+	if err != nil {
+		t.Fatalf("ParseFormData returned error: %v", err)
+	}
+	if event.Name != "Tabloide Marcos" || event.RegionID != 144 || event.EndValidityDate.Day() != 10 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.File.Name != "pagina.png" || event.File.Size != int64(len(content)) || !bytes.Equal(event.File.Content, content) {
+		t.Errorf("unexpected file %s", event.File)
+	}
+}
+
+func TestParseFormDataLimits(t *testing.T) {
+	content := encodeTestPNG(t, 40, 20)
+
+	tests := []struct {
+		name   string
+		limits UploadLimits
+	}{
+		{"page", UploadLimits{MaxPageBytes: int64(len(content)) - 1, MaxRequestBytes: 1 << 20}},
+		{"request", UploadLimits{MaxPageBytes: 1 << 20, MaxRequestBytes: int64(len(content))}},
+	}
+	for _, test := range tests {
+		_, err := ParseFormData(uploadContext(t, "image/png", content), test.limits)
+		var tooLargeError *UploadTooLargeError
+		if !errors.As(err, &tooLargeError) {
+			t.Errorf("%s limit: ParseFormData returned %v, expected an UploadTooLargeError", test.name, err)
+		}
+	}
+}
+
+func TestParseFormDataSniffsDeclaredType(t *testing.T) {
+	_, err := ParseFormData(uploadContext(t, "image/jpeg", encodeTestPNG(t, 40, 20)), UploadLimitsFromEnv())
+	if code := validationCode(err); code != ErrorImageTypeMismatch {
+		t.Errorf("ParseFormData returned %v, expected %s", err, ErrorImageTypeMismatch)
+	}
+}
+
+func TestParseFormDataRejectsSecondFile(t *testing.T) {
+	spoolDir := t.TempDir()
+	t.Setenv("TMPDIR", spoolDir)
+	archive := buildZip(t, map[string][]byte{"pagina-1.png": encodeTestPNG(t, 40, 20)}, []string{"pagina-1.png"})
+
+	_, err := ParseFormData(uploadContext(t, "application/zip", archive, archive), UploadLimitsFromEnv())
+	if err == nil {
+		t.Fatal("ParseFormData returned no error for a form with two files")
+	}
+	if spooled, _ := os.ReadDir(spoolDir); len(spooled) != 0 {
+		t.Errorf("ParseFormData left %d spooled files", len(spooled))
+	}
+}
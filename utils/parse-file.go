@@ -2,69 +2,141 @@
 package utils
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"os"
 	"test/lambda/interfaces"
 )
 
-// parseFile parses the given multipart file header.
-// It returns a File object containing file metadata or an error if parsing fails.
-//
-// Example:
-//
-//	file, err := parseFile(fileHeader)
-//	if err != nil {
-//	    fmt.Println("Error:", err)
-//	    return
-//	}
-//	fmt.Println("Parsed file:", file)
-func parseFile(file *multipart.FileHeader) (interfaces.File, error) {
-	fileReader, err := file.Open()
-	if err != nil {
-		return interfaces.File{}, fmt.Errorf("failed to open file: %w", err)
+// ErrorUploadTooLarge is the code of uploads exceeding the UploadLimits.
+const ErrorUploadTooLarge = "ERROR_UPLOAD_TOO_LARGE"
+
+// UploadTooLargeError represents a request or a page exceeding its UploadLimits. It is answered with 413.
+type UploadTooLargeError struct {
+	Limit int64  // Limit exceeded, in bytes.
+	What  string // What exceeded it: "request" or the name of the file.
+}
+
+func (e *UploadTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %s exceeds %d bytes", ErrorUploadTooLarge, e.What, e.Limit)
+}
+
+// UploadLimits bounds the size of upload requests.
+type UploadLimits struct {
+	MaxPageBytes    int64 // Maximum size of one uploaded page image.
+	MaxRequestBytes int64 // Maximum size of a whole request, ZIP archives included.
+}
+
+// UploadLimitsFromEnv reads the upload limits from the UPLOAD_MAX_PAGE_BYTES and UPLOAD_MAX_REQUEST_BYTES
+// environment variables.
+func UploadLimitsFromEnv() UploadLimits {
+	return UploadLimits{
+		MaxPageBytes:    int64(GetEnvInt("UPLOAD_MAX_PAGE_BYTES", 10<<20)),
+		MaxRequestBytes: int64(GetEnvInt("UPLOAD_MAX_REQUEST_BYTES", 100<<20)),
 	}
-	defer func(fileReader multipart.File) {
-		err := fileReader.Close()
-		if err != nil {
-			fmt.Println("Failed to close file reader:", err)
-		}
-	}(fileReader)
-
-	return interfaces.File{
-		Name:        file.Filename,
-		ContentType: file.Header.Get("Content-Type"),
-		Size:        file.Size,
-		Data:        file,
-	}, nil
 }
 
-// ReadFileContent reads the content of the file from the provided *multipart.FileHeader and returns it as a []byte
-//
-// Example:
-//
-//	fileContent, err := ReadFileContent(fileHeader)
-//	if err != nil {
-//	    fmt.Println("Error:", err)
-//	    return
-//	}
-//	fmt.Println("File content:", string(fileContent))
-func ReadFileContent(fileHeader *multipart.FileHeader) ([]byte, error) {
-	// Oppen the file from the multipart form data
-	file, err := fileHeader.Open()
+// sniffLength is the number of bytes http.DetectContentType looks at.
+const sniffLength = 512
+
+// readFilePart reads an uploaded file from its multipart part. The first bytes are sniffed and checked against
+// the declared content type before the rest is read, so mismatching files are rejected without reading them.
+// The rest is read as readFileContent does.
+// It returns an *UploadTooLargeError when the file exceeds its limit.
+func readFilePart(part *multipart.Part, limits UploadLimits) (interfaces.File, error) {
+	file := interfaces.File{
+		Name:        part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+	}
+
+	head, err := readHead(part)
 	if err != nil {
+		return file, err
+	}
+	if err := CheckDeclaredContentType(file.ContentType, head); err != nil {
+		return file, err
+	}
+
+	return file, readFileContent(&file, head, part, limits)
+}
+
+// readHead reads the first sniffLength bytes of a file, or all of it when it is shorter.
+func readHead(reader io.Reader) ([]byte, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
 		return nil, err
 	}
-	defer func(file multipart.File) {
-		err := file.Close()
-		if err != nil {
-			fmt.Println("Failed to close file reader:", err)
+	return head[:n], nil
+}
+
+// readFileContent reads the content of a file whose first bytes, head, were already read from reader.
+// Images are bounded by MaxPageBytes and kept in file.Content. ZIP archives hold many pages, so they are bounded
+// only by MaxRequestBytes and written to a temporary file, file.Path, instead of being kept in memory; the caller
+// removes it with RemoveSpooledFiles.
+// It returns an *UploadTooLargeError when the file exceeds its limit.
+func readFileContent(file *interfaces.File, head []byte, reader io.Reader, limits UploadLimits) error {
+	if !IsZip(head) {
+		var content bytes.Buffer
+		content.Write(head)
+		if _, err := io.Copy(&content, io.LimitReader(reader, limits.MaxPageBytes+1-int64(len(head)))); err != nil {
+			return err
 		}
-	}(file)
+		if int64(content.Len()) > limits.MaxPageBytes {
+			return &UploadTooLargeError{Limit: limits.MaxPageBytes, What: file.Name}
+		}
+		file.Content = content.Bytes()
+		file.Size = int64(len(file.Content))
+		return nil
+	}
 
-	fileContent, err := io.ReadAll(file)
+	spool, err := os.CreateTemp("", "upload-*.zip")
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer spool.Close()
+	size, err := io.Copy(spool, io.MultiReader(bytes.NewReader(head), io.LimitReader(reader, limits.MaxRequestBytes+1-int64(len(head)))))
+	if err == nil && size > limits.MaxRequestBytes {
+		err = &UploadTooLargeError{Limit: limits.MaxRequestBytes, What: file.Name}
+	}
+	if err != nil {
+		os.Remove(spool.Name())
+		return err
+	}
+
+	file.Path = spool.Name()
+	file.Size = size
+	return nil
+}
+
+// OpenFile opens the content of a file, whether it is kept in memory or in its temporary file.
+func OpenFile(file interfaces.File) (io.ReadSeekCloser, error) {
+	if file.Path != "" {
+		return os.Open(file.Path)
+	}
+	return nopCloser{bytes.NewReader(file.Content)}, nil
+}
+
+// nopCloser is a bytes.Reader with a Close method doing nothing.
+type nopCloser struct {
+	*bytes.Reader
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// RemoveSpooledFiles removes the temporary files of files, once they are no longer read.
+func RemoveSpooledFiles(files ...interfaces.File) {
+	for _, file := range files {
+		if file.Path != "" {
+			if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Println("err de RemoveSpooledFiles", file.Path, err)
+			}
+		}
 	}
-	return fileContent, nil
 }
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"test/lambda/interfaces"
+)
+
+// ParseTabloidManifest parses a tabloid manifest from its JSON body.
+// It returns an error if the body is not valid JSON or lists no files.
+//
+// Example:
+//
+//	manifest, err := ParseTabloidManifest([]byte(`{"name":"Ofertas","region_id":144,
+//	    "start_validity_date":"2024-04-08","end_validity_date":"2024-04-10","files":["RPA/inbox/ofertas.zip"]}`))
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	fmt.Println(manifest.Name, manifest.Files)
+func ParseTabloidManifest(body []byte) (*interfaces.TabloidManifest, error) {
+	var manifest interfaces.TabloidManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, errors.New("invalid manifest: no files")
+	}
+	return &manifest, nil
+}
+
+// ManifestRequestEvent builds the request event of a manifest whose files were read into files, parsing its dates
+// as the upload form does. The first file stands for the upload in the event; every file is validated.
+// It returns an error if a date or a file is invalid.
+func ManifestRequestEvent(manifest interfaces.TabloidManifest, files []interfaces.File) (*interfaces.RequestEvent, error) {
+	if len(files) == 0 {
+		return nil, errors.New("invalid manifest: no files")
+	}
+	for _, file := range files {
+		if err := ValidateStruct(file); err != nil {
+			return nil, fmt.Errorf("invalid file %s: %w", file.Name, err)
+		}
+	}
+
+	event := &interfaces.RequestEvent{Name: manifest.Name, RegionID: manifest.RegionID, File: files[0]}
+	var err error
+	if event.StartValidityDate, err = parseDate(manifest.StartValidityDate); err != nil {
+		return nil, fmt.Errorf("failed to parse start_validity_date: %w", err)
+	}
+	if event.EndValidityDate, err = parseDate(manifest.EndValidityDate); err != nil {
+		return nil, fmt.Errorf("failed to parse end_validity_date: %w", err)
+	}
+	return event, nil
+}
+
+// FileFromContent describes a page file read from storage rather than uploaded, as FileFromReader does.
+func FileFromContent(name string, content []byte, limits UploadLimits) (interfaces.File, error) {
+	return FileFromReader(name, bytes.NewReader(content), limits)
+}
+
+// FileFromReader reads a page file from storage rather than from an upload: its content type is sniffed,
+// as no client declares it. Files are bounded and kept like uploads: images in memory within limits.MaxPageBytes,
+// ZIP archives in a temporary file within limits.MaxRequestBytes (see readFileContent).
+// It returns an *UploadTooLargeError when the file exceeds its limit.
+func FileFromReader(name string, reader io.Reader, limits UploadLimits) (interfaces.File, error) {
+	head, err := readHead(reader)
+	if err != nil {
+		return interfaces.File{Name: name}, err
+	}
+	file := interfaces.File{
+		Name:        name,
+		ContentType: strings.Split(http.DetectContentType(head), ";")[0],
+	}
+	return file, readFileContent(&file, head, reader, limits)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"test/lambda/interfaces"
+	"testing"
+)
+
+func TestParseTabloidManifest(t *testing.T) {
+	manifest, err := ParseTabloidManifest([]byte(`{"name":"Ofertas","region_id":144,
+		"start_validity_date":"2024-04-08","end_validity_date":"2024-04-10","files":["pagina-1.png","pagina-2.png"]}`))
+	if err != nil {
+		t.Fatalf("ParseTabloidManifest returned error: %v", err)
+	}
+	if manifest.Name != "Ofertas" || manifest.RegionID != 144 || len(manifest.Files) != 2 {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+
+	for _, body := range []string{`{"name":"Ofertas"`, `{"name":"Ofertas","files":[]}`} {
+		if _, err := ParseTabloidManifest([]byte(body)); err == nil {
+			t.Errorf("ParseTabloidManifest(%s) returned no error", body)
+		}
+	}
+}
+
+func TestManifestRequestEvent(t *testing.T) {
+	limits := UploadLimits{MaxPageBytes: 1 << 20, MaxRequestBytes: 2 << 20}
+	page, err := FileFromContent("pagina-1.png", encodeTestPNG(t, 40, 20), limits)
+	if err != nil {
+		t.Fatalf("FileFromContent returned error: %v", err)
+	}
+	if page.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png", page.ContentType)
+	}
+
+	manifest := interfaces.TabloidManifest{Name: "Ofertas", RegionID: 144, StartValidityDate: "2024-04-08", EndValidityDate: "2024-04-10"}
+	event, err := ManifestRequestEvent(manifest, []interfaces.File{page})
+	if err != nil {
+		t.Fatalf("ManifestRequestEvent returned error: %v", err)
+	}
+	if event.Name != "Ofertas" || event.EndValidityDate.Day() != 10 || event.File.Name != "pagina-1.png" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	manifest.EndValidityDate = "10/04/2024"
+	if _, err := ManifestRequestEvent(manifest, []interfaces.File{page}); err == nil {
+		t.Error("ManifestRequestEvent accepted an invalid date")
+	}
+
+	text, _ := FileFromContent("notas.txt", []byte("not an image"), limits)
+	manifest.EndValidityDate = "2024-04-10"
+	if _, err := ManifestRequestEvent(manifest, []interfaces.File{page, text}); err == nil {
+		t.Error("ManifestRequestEvent accepted a text file")
+	}
+}
+
+func TestFileFromContentLimits(t *testing.T) {
+	limits := UploadLimits{MaxPageBytes: 100, MaxRequestBytes: 1 << 20}
+
+	_, err := FileFromContent("pagina-1.png", encodeTestPNG(t, 400, 400), limits)
+	var tooLarge *UploadTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 100 {
+		t.Errorf("expected an UploadTooLargeError for the page, got %v", err)
+	}
+
+	archive := buildZip(t, map[string][]byte{"pagina-1.png": encodeTestPNG(t, 400, 400)}, []string{"pagina-1.png"})
+	file, err := FileFromContent("paginas.zip", archive, limits)
+	defer RemoveSpooledFiles(file)
+	if err != nil || file.ContentType != "application/zip" {
+		t.Errorf("FileFromContent(zip) = %q, %v", file.ContentType, err)
+	}
+	if file.Path == "" || file.Content != nil || file.Size != int64(len(archive)) {
+		t.Errorf("expected the archive in a temporary file, got %s (path %q)", file, file.Path)
+	}
+	spooled, err := os.ReadFile(file.Path)
+	if err != nil || !bytes.Equal(spooled, archive) {
+		t.Errorf("temporary file does not hold the archive: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// ReadinessCheck is one dependency checked by the readiness endpoint.
+type ReadinessCheck struct {
+	Name  string                          // What is checked, such as "mysql".
+	Check func(ctx context.Context) error // Returns an error if the dependency cannot be used.
+}
+
+// RunReadinessChecks runs the checks concurrently, each one given timeout to pass, and reports the latency and the
+// result of each. A check that does not return within its timeout fails, even if it ignores its context.
+//
+// Example:
+//
+//	report := RunReadinessChecks(ctx, 3*time.Second, []ReadinessCheck{{Name: "mysql", Check: repository.Ping}})
+//	fmt.Println(report.Status) // "ok" or "falha"
+func RunReadinessChecks(ctx context.Context, timeout time.Duration, checks []ReadinessCheck) interfaces.ReadinessReport {
+	report := interfaces.ReadinessReport{Status: interfaces.HealthStatusOK, Checks: make([]interfaces.HealthCheck, len(checks))}
+	done := make(chan struct{}, len(checks))
+	for i, check := range checks {
+		go func(i int, check ReadinessCheck) {
+			report.Checks[i] = runReadinessCheck(ctx, timeout, check)
+			done <- struct{}{}
+		}(i, check)
+	}
+	for range checks {
+		<-done
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != interfaces.HealthStatusOK {
+			report.Status = interfaces.HealthStatusFail
+		}
+	}
+	return report
+}
+
+// PublicReadinessReport returns report without the errors of its checks, which can name hosts and buckets, for
+// routes open to anyone.
+func PublicReadinessReport(report interfaces.ReadinessReport) interfaces.ReadinessReport {
+	public := interfaces.ReadinessReport{Status: report.Status, Checks: make([]interfaces.HealthCheck, len(report.Checks))}
+	for i, check := range report.Checks {
+		check.Error = ""
+		public.Checks[i] = check
+	}
+	return public
+}
+
+// runReadinessCheck runs one check within timeout.
+func runReadinessCheck(ctx context.Context, timeout time.Duration, check ReadinessCheck) interfaces.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	health := interfaces.HealthCheck{Name: check.Name, Status: interfaces.HealthStatusOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		health.Status = interfaces.HealthStatusFail
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// sensitiveConfigWords are the parts of the names of the environment variables whose values are masked.
+var sensitiveConfigWords = []string{"SECRET", "PASSWORD", "PRIVATE", "TOKEN", "CREDENTIAL", "KEY"}
+
+// MaskedConfig returns the environment variables of environ ("NAME=value", as os.Environ returns them) by name,
+// with the values of the sensitive ones (names containing SECRET, PASSWORD, PRIVATE, TOKEN, CREDENTIAL or KEY)
+// replaced by "****". Empty values are kept, as they show the variable is not set.
+//
+// Example:
+//
+//	MaskedConfig([]string{"STAGE=dev", "CLOUDFRONT_PRIVATE_KEY=-----BEGIN"}) // {"STAGE": "dev", "CLOUDFRONT_PRIVATE_KEY": "****"}
+func MaskedConfig(environ []string) map[string]string {
+	config := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if value != "" && isSensitiveConfig(name) {
+			value = "****"
+		}
+		config[name] = value
+	}
+	return config
+}
+
+// isSensitiveConfig reports whether the value of an environment variable must be masked.
+func isSensitiveConfig(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range sensitiveConfigWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
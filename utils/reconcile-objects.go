@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"test/lambda/interfaces"
+	"time"
+)
+
+// ReconcileObjects compares the objects listed under prefixes with the keys referenced by the database.
+// Unreferenced objects written before now-grace are orphans; newer ones may belong to an upload still in
+// progress and are reported apart. Referenced keys under one of the prefixes with no object are missing.
+// Keys outside the prefixes are ignored, as their objects were not listed.
+//
+// Example:
+//
+//	report := ReconcileObjects(objects, referencedKeys, []string{"RPA/v3/"}, time.Now(), 24*time.Hour)
+//	fmt.Println(len(report.Orphans), "orphans,", len(report.Missing), "missing")
+func ReconcileObjects(objects []interfaces.StoredObject, referenced map[string]bool, prefixes []string,
+	now time.Time, grace time.Duration) interfaces.ReconciliationReport {
+	report := interfaces.ReconciliationReport{
+		CheckedAt:     now,
+		Prefixes:      prefixes,
+		Objects:       len(objects),
+		Referenced:    len(referenced),
+		Orphans:       []interfaces.StoredObject{},
+		RecentOrphans: []interfaces.StoredObject{},
+		Missing:       []string{},
+		Processed:     []string{},
+	}
+
+	stored := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		stored[object.Key] = true
+		if referenced[object.Key] {
+			continue
+		}
+		if object.LastModified.After(now.Add(-grace)) {
+			report.RecentOrphans = append(report.RecentOrphans, object)
+		} else {
+			report.Orphans = append(report.Orphans, object)
+		}
+	}
+
+	for key := range referenced {
+		if !stored[key] && hasAnyPrefix(key, prefixes) {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+	sort.Strings(report.Missing)
+
+	return report
+}
+
+// hasAnyPrefix reports whether key starts with one of the prefixes.
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"reflect"
+	"test/lambda/interfaces"
+	"testing"
+	"time"
+)
+
+func TestReconcileObjects(t *testing.T) {
+	now := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+	objects := []interfaces.StoredObject{
+		{Key: "RPA/v3/1/page-1.png", LastModified: now.AddDate(0, 0, -10)},
+		{Key: "RPA/v3/1/page-1-w200.png", LastModified: now.AddDate(0, 0, -10)},
+		{Key: "RPA/v3/2/abandoned.png", LastModified: now.AddDate(0, 0, -3)},
+		{Key: "RPA/v3/3/uploading.png", LastModified: now.Add(-time.Hour)},
+	}
+	referenced := map[string]bool{
+		"RPA/v3/1/page-1.png":        true,
+		"RPA/v3/1/page-1-w200.png":   true,
+		"RPA/v3/4/never-written.png": true,
+		"legacy/outside-prefix.png":  true,
+	}
+
+	report := ReconcileObjects(objects, referenced, []string{"RPA/v3/"}, now, 24*time.Hour)
+
+	if report.Objects != 4 || report.Referenced != 4 {
+		t.Errorf("unexpected counts: %d objects, %d referenced", report.Objects, report.Referenced)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].Key != "RPA/v3/2/abandoned.png" {
+		t.Errorf("unexpected orphans: %+v", report.Orphans)
+	}
+	if len(report.RecentOrphans) != 1 || report.RecentOrphans[0].Key != "RPA/v3/3/uploading.png" {
+		t.Errorf("unexpected recent orphans: %+v", report.RecentOrphans)
+	}
+	if !reflect.DeepEqual(report.Missing, []string{"RPA/v3/4/never-written.png"}) {
+		t.Errorf("unexpected missing keys: %v", report.Missing)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ResizeImage scales an image down to the given width, keeping its aspect ratio and applying its EXIF orientation.
+// JPEG images are resized to JPEG and every other format to PNG.
+// It returns false without resizing when the image is not wider than width.
+//
+// Example:
+//
+//	thumbnail, resized, err := ResizeImage(content, 200)
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	if resized {
+//	    fmt.Println("Thumbnail has", len(thumbnail), "bytes")
+//	}
+func ResizeImage(content []byte, width int) ([]byte, bool, error) {
+	source, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode image: %w", err)
+	}
+	source = OrientImage(source, JpegOrientation(content))
+
+	bounds := source.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return nil, false, nil
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), source, bounds, draw.Over, nil)
+
+	var buffer bytes.Buffer
+	switch format {
+	case "png", "gif", "webp":
+		// WebP cannot be encoded in pure Go; PNG keeps the transparency of GIF and WebP sources
+		err = png.Encode(&buffer, resized)
+	default:
+		err = jpeg.Encode(&buffer, resized, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buffer.Bytes(), true, nil
+}
+
+// ParseIntList parses a comma-separated list of positive integers, such as "200,600,1200".
+// Blank items are ignored.
+func ParseIntList(value string) ([]int, error) {
+	var numbers []int
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		number, err := strconv.Atoi(item)
+		if err != nil || number <= 0 {
+			return nil, fmt.Errorf("invalid positive integer: %q", item)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
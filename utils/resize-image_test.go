@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			source.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, source); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	content := encodeTestPNG(t, 400, 600)
+
+	resized, ok, err := ResizeImage(content, 200)
+	if err != nil || !ok {
+		t.Fatalf("ResizeImage returned %v, %v", ok, err)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(resized))
+	if err != nil {
+		t.Fatalf("ResizeImage returned an undecodable image: %v", err)
+	}
+	if format != "png" || config.Width != 200 || config.Height != 300 {
+		t.Errorf("ResizeImage returned a %s of %dx%d, expected a png of 200x300", format, config.Width, config.Height)
+	}
+}
+
+func TestResizeImage_NoUpscale(t *testing.T) {
+	_, ok, err := ResizeImage(encodeTestPNG(t, 100, 100), 200)
+	if err != nil || ok {
+		t.Errorf("ResizeImage returned %v, %v, expected the image not to be resized", ok, err)
+	}
+}
+
+func TestParseIntList(t *testing.T) {
+	numbers, err := ParseIntList(" 200, 600,,1200 ")
+	if err != nil || len(numbers) != 3 || numbers[0] != 200 || numbers[2] != 1200 {
+		t.Errorf("ParseIntList returned %v, %v", numbers, err)
+	}
+	if _, err := ParseIntList("200,abc"); err == nil {
+		t.Errorf("ParseIntList did not return an error for an invalid item")
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignCloudFrontURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	resourceURL := "https://d111.cloudfront.net/RPA/v3/1/campanha-1-pagina-1.png"
+	expires := time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC)
+
+	signed, err := SignCloudFrontURL(resourceURL, "K2JCJMDEHXQW5F", key, expires)
+	if err != nil {
+		t.Fatalf("SignCloudFrontURL returned error: %v", err)
+	}
+	if !strings.HasPrefix(signed, resourceURL+"?") {
+		t.Fatalf("SignCloudFrontURL returned %q, expected it to extend %q", signed, resourceURL)
+	}
+
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("signed url does not parse: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("Expires") != "1712793600" || query.Get("Key-Pair-Id") != "K2JCJMDEHXQW5F" {
+		t.Errorf("unexpected query %v", query)
+	}
+
+	// Verify the signature the way CloudFront does, against the canned policy
+	encoded := strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(query.Get("Signature"))
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("signature is not base64: %v", err)
+	}
+	policy := `{"Statement":[{"Resource":"` + resourceURL + `","Condition":{"DateLessThan":{"AWS:EpochTime":1712793600}}}]}`
+	hash := sha1.Sum([]byte(policy))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hash[:], signature); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestSignCloudFrontURLWithQuery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	signed, err := SignCloudFrontURL("https://d111.cloudfront.net/page.png?v=2", "K1", key, time.Unix(1712793600, 0))
+	if err != nil {
+		t.Fatalf("SignCloudFrontURL returned error: %v", err)
+	}
+	if !strings.HasPrefix(signed, "https://d111.cloudfront.net/page.png?v=2&Expires=1712793600&") {
+		t.Errorf("SignCloudFrontURL returned %q", signed)
+	}
+}
+
+func TestParseRSAPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey returned error: %v", err)
+	}
+
+	for name, block := range map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := ParseRSAPrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Errorf("ParseRSAPrivateKey(%s) returned error: %v", name, err)
+			continue
+		}
+		if !parsed.Equal(key) {
+			t.Errorf("ParseRSAPrivateKey(%s) returned a different key", name)
+		}
+	}
+
+	if _, err := ParseRSAPrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("ParseRSAPrivateKey accepted invalid input")
+	}
+}
+
+func TestSignedURLExpiry(t *testing.T) {
+	now := time.Date(2024, 4, 10, 23, 50, 0, 0, time.UTC)
+	validityEnd := time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		lifetime time.Duration
+		notAfter time.Time
+		expected time.Time
+	}{
+		{5 * time.Minute, validityEnd, now.Add(5 * time.Minute)},
+		{15 * time.Minute, validityEnd, validityEnd},
+		{15 * time.Minute, time.Time{}, now.Add(15 * time.Minute)},
+	}
+	for _, test := range tests {
+		if expires := SignedURLExpiry(now, test.lifetime, test.notAfter); !expires.Equal(test.expected) {
+			t.Errorf("SignedURLExpiry(%v, %v) returned %v, expected %v", test.lifetime, test.notAfter, expires, test.expected)
+		}
+	}
+}
+
+func TestSignedURLTime(t *testing.T) {
+	lifetime := 15 * time.Minute
+	first := SignedURLTime(time.Date(2024, 4, 10, 23, 45, 10, 0, time.UTC), lifetime)
+	second := SignedURLTime(time.Date(2024, 4, 10, 23, 48, 40, 0, time.UTC), lifetime)
+	if !first.Equal(second) || !first.Equal(time.Date(2024, 4, 10, 23, 45, 0, 0, time.UTC)) {
+		t.Errorf("SignedURLTime returned %v and %v, expected both at 23:45", first, second)
+	}
+
+	next := SignedURLTime(time.Date(2024, 4, 10, 23, 49, 0, 0, time.UTC), lifetime)
+	if !next.Equal(first.Add(lifetime / 4)) {
+		t.Errorf("SignedURLTime returned %v, expected the next quarter", next)
+	}
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// JpegOrientation reads the EXIF orientation (1 to 8) of a JPEG image.
+// It returns 1, the upright orientation, when the image has no EXIF orientation.
+//
+// Example:
+//
+//	if JpegOrientation(content) == 6 {
+//	    fmt.Println("The photo was taken with the phone rotated 90 degrees")
+//	}
+func JpegOrientation(content []byte) int {
+	for _, segment := range jpegSegments(content) {
+		if segment.marker != 0xE1 || !bytes.HasPrefix(segment.data, []byte("Exif\x00\x00")) {
+			continue
+		}
+		if orientation := exifOrientation(segment.data[6:]); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+	}
+	return 1
+}
+
+// StripJpegMetadata re-encodes a JPEG image carrying metadata (EXIF, XMP, IPTC, comments), rotating
+// and flipping the pixels as its EXIF orientation says, so the stored image is upright and has no metadata.
+// Other formats, and JPEG images without metadata, are returned unchanged.
+//
+// Example:
+//
+//	stripped, err := StripJpegMetadata(photo)
+//	if err != nil {
+//	    fmt.Println("Error:", err)
+//	    return
+//	}
+//	fmt.Println(JpegOrientation(stripped)) // 1
+func StripJpegMetadata(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte{0xFF, 0xD8}) || !hasJpegMetadata(content) {
+		return content, nil
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode jpeg: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, OrientImage(decoded, JpegOrientation(content)), &jpeg.Options{Quality: 92}); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// OrientImage rotates and flips an image according to an EXIF orientation, returning it upright.
+// The source is converted to RGBA once, then its pixels are moved as 4-byte runs of the pixel buffers.
+func OrientImage(source image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return source
+	}
+
+	bounds := source.Bounds()
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	rgba, ok := source.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, sourceWidth, sourceHeight))
+		draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+	}
+
+	width, height := sourceWidth, sourceHeight
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	oriented := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < sourceHeight; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+sourceWidth*4]
+		for x := 0; x < sourceWidth; x++ {
+			var targetX, targetY int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				targetX, targetY = sourceWidth-1-x, y
+			case 3: // Rotated 180 degrees
+				targetX, targetY = sourceWidth-1-x, sourceHeight-1-y
+			case 4: // Mirrored vertically
+				targetX, targetY = x, sourceHeight-1-y
+			case 5: // Mirrored along the top-left diagonal
+				targetX, targetY = y, x
+			case 6: // Rotated 90 degrees clockwise
+				targetX, targetY = sourceHeight-1-y, x
+			case 7: // Mirrored along the top-right diagonal
+				targetX, targetY = sourceHeight-1-y, sourceWidth-1-x
+			case 8: // Rotated 90 degrees counterclockwise
+				targetX, targetY = y, sourceWidth-1-x
+			}
+			offset := targetY*oriented.Stride + targetX*4
+			copy(oriented.Pix[offset:offset+4], row[x*4:x*4+4])
+		}
+	}
+	return oriented
+}
+
+// jpegSegment is a marker segment found before the image data of a JPEG.
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// jpegSegments lists the marker segments of a JPEG up to the start of the scan data.
+func jpegSegments(content []byte) []jpegSegment {
+	var segments []jpegSegment
+	if !bytes.HasPrefix(content, []byte{0xFF, 0xD8}) {
+		return segments
+	}
+
+	position := 2
+	for position+4 <= len(content) && content[position] == 0xFF {
+		marker := content[position+1]
+		if marker == 0xD9 || marker == 0xDA { // End of image or start of scan
+			break
+		}
+		length := int(binary.BigEndian.Uint16(content[position+2 : position+4]))
+		if length < 2 || position+2+length > len(content) {
+			break
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: content[position+4 : position+2+length]})
+		position += 2 + length
+	}
+	return segments
+}
+
+// hasJpegMetadata reports whether the JPEG has APP1..APP15 (EXIF, XMP, IPTC...) or comment segments.
+func hasJpegMetadata(content []byte) bool {
+	for _, segment := range jpegSegments(content) {
+		if (segment.marker >= 0xE1 && segment.marker <= 0xEF) || segment.marker == 0xFE {
+			return true
+		}
+	}
+	return false
+}
+
+// exifOrientation reads the orientation tag (0x0112) from the first IFD of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// encodeTestJpegWithOrientation encodes a JPEG of width x height with an EXIF APP1 segment
+// holding the given orientation, plus a fake GPS comment.
+func encodeTestJpegWithOrientation(t *testing.T, width, height, orientation int) []byte {
+	t.Helper()
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	source.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, source, nil); err != nil {
+		t.Fatalf("failed to encode test jpeg: %v", err)
+	}
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:2], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:4], 3)
+	binary.BigEndian.PutUint32(entry[4:8], 1)
+	binary.BigEndian.PutUint16(entry[8:10], uint16(orientation))
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	comment := []byte("GPS -23.5505 -46.6333")
+
+	var content bytes.Buffer
+	content.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&content, binary.BigEndian, uint16(len(app1)+2))
+	content.Write(app1)
+	content.Write([]byte{0xFF, 0xFE})
+	binary.Write(&content, binary.BigEndian, uint16(len(comment)+2))
+	content.Write(comment)
+	content.Write(encoded.Bytes()[2:])
+	return content.Bytes()
+}
+
+func TestJpegOrientation(t *testing.T) {
+	if orientation := JpegOrientation(encodeTestJpegWithOrientation(t, 8, 4, 6)); orientation != 6 {
+		t.Errorf("JpegOrientation returned %d, expected 6", orientation)
+	}
+	if orientation := JpegOrientation(encodeTestPNG(t, 8, 4)); orientation != 1 {
+		t.Errorf("JpegOrientation returned %d for a png, expected 1", orientation)
+	}
+}
+
+func TestStripJpegMetadata(t *testing.T) {
+	content := encodeTestJpegWithOrientation(t, 8, 4, 6)
+
+	stripped, err := StripJpegMetadata(content)
+	if err != nil {
+		t.Fatalf("StripJpegMetadata returned an error: %v", err)
+	}
+	if hasJpegMetadata(stripped) || bytes.Contains(stripped, []byte("GPS")) {
+		t.Errorf("StripJpegMetadata kept metadata segments")
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("StripJpegMetadata returned an undecodable jpeg: %v", err)
+	}
+	if config.Width != 4 || config.Height != 8 {
+		t.Errorf("StripJpegMetadata returned %dx%d, expected the rotated 4x8", config.Width, config.Height)
+	}
+}
+
+func TestStripJpegMetadata_Unchanged(t *testing.T) {
+	png := encodeTestPNG(t, 4, 4)
+	if stripped, err := StripJpegMetadata(png); err != nil || !bytes.Equal(stripped, png) {
+		t.Errorf("StripJpegMetadata changed a png (err %v)", err)
+	}
+
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+	if stripped, err := StripJpegMetadata(plain.Bytes()); err != nil || !bytes.Equal(stripped, plain.Bytes()) {
+		t.Errorf("StripJpegMetadata changed a jpeg without metadata (err %v)", err)
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	source := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	source.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	// Orientation 6 stores the image rotated 90 degrees counterclockwise; the top-left pixel goes to the top-right
+	oriented := OrientImage(source, 6)
+	if oriented.Bounds().Dx() != 2 || oriented.Bounds().Dy() != 3 {
+		t.Fatalf("OrientImage returned bounds %v, expected 2x3", oriented.Bounds())
+	}
+	if r, _, _, _ := oriented.At(1, 0).RGBA(); r == 0 {
+		t.Errorf("OrientImage did not move the top-left pixel to the top-right")
+	}
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Error codes of the image validation pipeline.
+const (
+	ErrorImageType          = "ERROR_IMAGE_TYPE"            // The content is not a supported image type.
+	ErrorImageTypeMismatch  = "ERROR_IMAGE_TYPE_MISMATCH"   // The declared, sniffed and decoded types disagree.
+	ErrorImageCorrupt       = "ERROR_IMAGE_CORRUPT"         // The image cannot be fully decoded, e.g. it is truncated.
+	ErrorImageTrailingData  = "ERROR_IMAGE_TRAILING_DATA"   // The image carries data after its end marker, as polyglot files do.
+	ErrorImageDimensions    = "ERROR_IMAGE_DIMENSIONS"      // The width or the height is out of the configured limits.
+	ErrorImageAspectRatio   = "ERROR_IMAGE_ASPECT_RATIO"    // The width/height ratio is out of the configured limits.
+	ErrorImageTooManyPixels = "ERROR_IMAGE_TOO_MANY_PIXELS" // The width*height exceeds the limit (decompression bomb).
+	ErrorImageAnimated      = "ERROR_IMAGE_ANIMATED"        // The image is an animated GIF.
+)
+
+// ImageValidationError represents an image rejected by the validation pipeline.
+type ImageValidationError struct {
+	Code    string // One of the ERROR_IMAGE_* codes.
+	Message string // Human readable reason.
+}
+
+func (e *ImageValidationError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func newImageValidationError(code, format string, args ...interface{}) error {
+	return &ImageValidationError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ImageLimits bounds the images accepted by ValidateImageContent.
+type ImageLimits struct {
+	MaxWidth       int     // Maximum width in pixels.
+	MaxHeight      int     // Maximum height in pixels.
+	MaxPixels      int     // Maximum width*height, checked from the header before decoding.
+	MinAspectRatio float64 // Minimum width/height ratio.
+	MaxAspectRatio float64 // Maximum width/height ratio.
+}
+
+// ImageLimitsFromEnv reads the image limits from the IMAGE_MAX_WIDTH, IMAGE_MAX_HEIGHT, IMAGE_MAX_PIXELS,
+// IMAGE_MIN_ASPECT_RATIO and IMAGE_MAX_ASPECT_RATIO environment variables.
+func ImageLimitsFromEnv() ImageLimits {
+	return ImageLimits{
+		MaxWidth:       GetEnvInt("IMAGE_MAX_WIDTH", 10000),
+		MaxHeight:      GetEnvInt("IMAGE_MAX_HEIGHT", 10000),
+		MaxPixels:      GetEnvInt("IMAGE_MAX_PIXELS", 50000000),
+		MinAspectRatio: GetEnvFloat("IMAGE_MIN_ASPECT_RATIO", 0.2),
+		MaxAspectRatio: GetEnvFloat("IMAGE_MAX_ASPECT_RATIO", 5),
+	}
+}
+
+// supportedImageTypes maps the sniffed content types to the format names of the image package.
+var supportedImageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/webp": "webp",
+	"image/gif":  "gif",
+}
+
+// ValidateImageContent runs the image validation pipeline: it sniffs the content type, reads the header
+// to check the dimensions before allocating any pixel, rejects animated GIFs and data after the end marker,
+// and finally decodes the whole image. Each kind of rejection is an *ImageValidationError with its own code.
+//
+// Example:
+//
+//	err := ValidateImageContent(content, ImageLimitsFromEnv())
+//	var validationError *ImageValidationError
+//	if errors.As(err, &validationError) {
+//	    fmt.Println("Rejected with", validationError.Code)
+//	}
+func ValidateImageContent(content []byte, limits ImageLimits) error {
+	contentType := http.DetectContentType(content)
+	format, ok := supportedImageTypes[contentType]
+	if !ok {
+		return newImageValidationError(ErrorImageType, "unsupported image type %s", contentType)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return newImageValidationError(ErrorImageCorrupt, "failed to read image header: %v", err)
+	}
+	if decodedFormat != format {
+		return newImageValidationError(ErrorImageTypeMismatch, "content sniffed as %s but decoded as %s", contentType, decodedFormat)
+	}
+
+	if config.Width < 1 || config.Height < 1 || config.Width > limits.MaxWidth || config.Height > limits.MaxHeight {
+		return newImageValidationError(ErrorImageDimensions, "image of %dx%d exceeds the limit of %dx%d",
+			config.Width, config.Height, limits.MaxWidth, limits.MaxHeight)
+	}
+	if int64(config.Width)*int64(config.Height) > int64(limits.MaxPixels) {
+		return newImageValidationError(ErrorImageTooManyPixels, "image of %dx%d exceeds the limit of %d pixels",
+			config.Width, config.Height, limits.MaxPixels)
+	}
+	aspectRatio := float64(config.Width) / float64(config.Height)
+	if aspectRatio < limits.MinAspectRatio || aspectRatio > limits.MaxAspectRatio {
+		return newImageValidationError(ErrorImageAspectRatio, "aspect ratio %.2f is out of the range %.2f..%.2f",
+			aspectRatio, limits.MinAspectRatio, limits.MaxAspectRatio)
+	}
+
+	if format == "gif" {
+		animated, err := IsAnimatedGif(content)
+		if err != nil {
+			return newImageValidationError(ErrorImageCorrupt, "%v", err)
+		}
+		if animated {
+			return newImageValidationError(ErrorImageAnimated, "animated gif is not supported")
+		}
+	}
+
+	if hasTrailingData(format, content) {
+		return newImageValidationError(ErrorImageTrailingData, "%s image has data after its end marker", format)
+	}
+
+	if _, _, err := image.Decode(bytes.NewReader(content)); err != nil {
+		return newImageValidationError(ErrorImageCorrupt, "failed to decode image: %v", err)
+	}
+
+	return nil
+}
+
+// CheckDeclaredContentType compares the content type declared by the client with the sniffed one.
+// Aliases such as image/jpg and application/x-zip-compressed are accepted; an empty or
+// application/octet-stream declaration means the client did not declare a type and is not compared.
+//
+// Example:
+//
+//	if err := CheckDeclaredContentType(file.ContentType, content); err != nil {
+//	    fmt.Println("Error:", err)
+//	}
+func CheckDeclaredContentType(declared string, content []byte) error {
+	declaredType, _, err := mime.ParseMediaType(declared)
+	if err != nil || declaredType == "application/octet-stream" {
+		return nil
+	}
+	declaredType = canonicalContentType(declaredType)
+	sniffedType := canonicalContentType(strings.Split(http.DetectContentType(content), ";")[0])
+
+	if declaredType != sniffedType {
+		return newImageValidationError(ErrorImageTypeMismatch, "declared content type %s does not match the content (%s)", declaredType, sniffedType)
+	}
+	return nil
+}
+
+// canonicalContentType maps content type aliases to the name used by http.DetectContentType.
+func canonicalContentType(contentType string) string {
+	switch strings.ToLower(contentType) {
+	case "image/jpg", "image/pjpeg":
+		return "image/jpeg"
+	case "application/x-zip-compressed", "application/x-zip":
+		return "application/zip"
+	}
+	return strings.ToLower(contentType)
+}
+
+// hasTrailingData reports whether the image has bytes after the end marker of its format.
+func hasTrailingData(format string, content []byte) bool {
+	switch format {
+	case "png":
+		// The last chunk is IEND: 4 bytes of length, the type and 4 bytes of CRC
+		return len(content) < 12 || !bytes.Equal(content[len(content)-8:len(content)-4], []byte("IEND"))
+	case "jpeg":
+		trimmed := bytes.TrimRight(content, "\x00")
+		return !bytes.HasSuffix(trimmed, []byte{0xFF, 0xD9})
+	case "gif":
+		return content[len(content)-1] != 0x3B
+	case "webp":
+		// The RIFF header declares the size of everything after its first 8 bytes, padded to an even size
+		declared := int(binary.LittleEndian.Uint32(content[4:8])) + 8
+		return len(content) > declared+declared%2
+	}
+	return false
+}
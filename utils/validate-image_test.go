@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+var testImageLimits = ImageLimits{MaxWidth: 1000, MaxHeight: 1000, MaxPixels: 500000, MinAspectRatio: 0.2, MaxAspectRatio: 5}
+
+func validationCode(err error) string {
+	var validationError *ImageValidationError
+	if errors.As(err, &validationError) {
+		return validationError.Code
+	}
+	return ""
+}
+
+func TestValidateImageContent_Valid(t *testing.T) {
+	webp, _ := base64.StdEncoding.DecodeString(webpPixel)
+	var jpegBuffer bytes.Buffer
+	jpeg.Encode(&jpegBuffer, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil)
+
+	for name, content := range map[string][]byte{
+		"png":  encodeTestPNG(t, 40, 30),
+		"jpeg": jpegBuffer.Bytes(),
+		"gif":  encodeTestGif(t, 1),
+		"webp": webp,
+	} {
+		if err := ValidateImageContent(content, testImageLimits); err != nil {
+			t.Errorf("ValidateImageContent(%s) returned an error: %v", name, err)
+		}
+	}
+}
+
+func TestValidateImageContent_Rejections(t *testing.T) {
+	valid := encodeTestPNG(t, 40, 30)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		limits   ImageLimits
+		expected string
+	}{
+		{"text", []byte("not an image at all"), testImageLimits, ErrorImageType},
+		{"truncated", valid[:len(valid)-20], testImageLimits, ErrorImageTrailingData},
+		{"corrupt header", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...), testImageLimits, ErrorImageCorrupt},
+		{"polyglot", append(append([]byte{}, valid...), []byte("PK\x03\x04payload")...), testImageLimits, ErrorImageTrailingData},
+		{"too wide", encodeTestPNG(t, 40, 30), ImageLimits{MaxWidth: 20, MaxHeight: 1000, MaxPixels: 500000, MinAspectRatio: 0.2, MaxAspectRatio: 5}, ErrorImageDimensions},
+		{"too many pixels", encodeTestPNG(t, 40, 30), ImageLimits{MaxWidth: 1000, MaxHeight: 1000, MaxPixels: 100, MinAspectRatio: 0.2, MaxAspectRatio: 5}, ErrorImageTooManyPixels},
+		{"aspect ratio", encodeTestPNG(t, 60, 10), testImageLimits, ErrorImageAspectRatio},
+		{"animated", encodeTestGif(t, 2), testImageLimits, ErrorImageAnimated},
+	}
+
+	for _, test := range tests {
+		if code := validationCode(ValidateImageContent(test.content, test.limits)); code != test.expected {
+			t.Errorf("%s: ValidateImageContent returned %q, expected %q", test.name, code, test.expected)
+		}
+	}
+}
+
+func TestValidateImageContent_TruncatedPixels(t *testing.T) {
+	valid := encodeTestPNG(t, 40, 30)
+	// Drop bytes from the middle of the pixel data, keeping the IEND chunk
+	truncated := append(append([]byte{}, valid[:len(valid)/2]...), valid[len(valid)-12:]...)
+
+	if code := validationCode(ValidateImageContent(truncated, testImageLimits)); code != ErrorImageCorrupt {
+		t.Errorf("ValidateImageContent returned %q, expected %q", code, ErrorImageCorrupt)
+	}
+}
+
+func TestCheckDeclaredContentType(t *testing.T) {
+	content := encodeTestPNG(t, 4, 4)
+
+	tests := []struct {
+		declared string
+		expected string
+	}{
+		{"image/png", ""},
+		{"image/PNG; charset=binary", ""},
+		{"", ""},
+		{"application/octet-stream", ""},
+		{"image/jpeg", ErrorImageTypeMismatch},
+		{"application/zip", ErrorImageTypeMismatch},
+	}
+	for _, test := range tests {
+		if code := validationCode(CheckDeclaredContentType(test.declared, content)); code != test.expected {
+			t.Errorf("CheckDeclaredContentType(%q) returned %q, expected %q", test.declared, code, test.expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of the webhook requests.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>".
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // Unix time the request was signed at.
+	WebhookEventHeader     = "X-Webhook-Event"     // Type of the event.
+	WebhookIDHeader        = "X-Webhook-Id"        // Deduplication ID of the event, the same on every attempt.
+)
+
+// SignWebhook signs the body of a webhook request sent at timestamp with the secret of the subscription.
+// The timestamp is signed along with the body, so receivers can reject replayed requests.
+//
+// Example:
+//
+//	timestamp := time.Now()
+//	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+//	request.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature and the timestamp headers of a webhook request, as receivers should: the
+// signature must match and the timestamp must be within tolerance of now.
+// It returns an error describing the first check that fails.
+func VerifyWebhook(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	signedAt := time.Unix(seconds, 0)
+	if now.Sub(signedAt) > tolerance || signedAt.Sub(now) > tolerance {
+		return errors.New("webhook timestamp outside of the tolerance")
+	}
+	if !strings.HasPrefix(signature, "sha256=") || !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, signedAt, body))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
+
+// WebhookBackoff returns how long to wait before the next attempt of a delivery that failed attempts times:
+// base doubled for each failure after the first, up to maxDelay.
+//
+// Example:
+//
+//	WebhookBackoff(1, 30*time.Second, 6*time.Hour) // 30s
+//	WebhookBackoff(4, 30*time.Second, 6*time.Hour) // 4m0s
+func WebhookBackoff(attempts int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
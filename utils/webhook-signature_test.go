@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	signedAt := time.Unix(1712577600, 0)
+	body := []byte(`{"type":"TabloidCreated"}`)
+	signature := SignWebhook("segredo", signedAt, body)
+
+	// echo -n '1712577600.{"type":"TabloidCreated"}' | openssl dgst -sha256 -hmac segredo
+	want := "sha256=a4224131da669edeb7a9b8788ab06c41eaffd04ec5af5237ad48c7912831ed04"
+	if signature != want {
+		t.Fatalf("SignWebhook = %q, want %q", signature, want)
+	}
+
+	if err := VerifyWebhook("segredo", signature, "1712577600", body, signedAt.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("VerifyWebhook returned error: %v", err)
+	}
+	if err := VerifyWebhook("outro", signature, "1712577600", body, signedAt, 5*time.Minute); err == nil {
+		t.Error("VerifyWebhook accepted the wrong secret")
+	}
+	if err := VerifyWebhook("segredo", signature, "1712577600", []byte(`{}`), signedAt, 5*time.Minute); err == nil {
+		t.Error("VerifyWebhook accepted a changed body")
+	}
+	if err := VerifyWebhook("segredo", signature, "1712577600", body, signedAt.Add(time.Hour), 5*time.Minute); err == nil {
+		t.Error("VerifyWebhook accepted an old timestamp")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		20: 6 * time.Hour,
+	} {
+		if got := WebhookBackoff(attempts, 30*time.Second, 6*time.Hour); got != want {
+			t.Errorf("WebhookBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// RunBounded calls task for every index in [0, n), running at most concurrency tasks at a time.
+// The first error cancels the context given to the tasks: tasks not started yet are skipped and running ones
+// should stop early. It waits for the running tasks and returns the first error.
+//
+// Example:
+//
+//	err := RunBounded(ctx, len(pages), 4, func(ctx context.Context, i int) error {
+//	    return upload(ctx, pages[i])
+//	})
+func RunBounded(ctx context.Context, n, concurrency int, task func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, max(concurrency, 1))
+
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := task(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context was cancelled before every task could start
+		return context.Cause(ctx)
+	}
+	return firstErr
+}
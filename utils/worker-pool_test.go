@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBounded(t *testing.T) {
+	var running, peak, done int32
+	results := make([]int, 20)
+
+	err := RunBounded(context.Background(), len(results), 3, func(ctx context.Context, i int) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&peak)
+			if current <= previous || atomic.CompareAndSwapInt32(&peak, previous, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("RunBounded returned error: %v", err)
+	}
+	if done != 20 || peak > 3 {
+		t.Errorf("ran %d tasks with %d at once, expected 20 with at most 3", done, peak)
+	}
+	for i, result := range results {
+		if result != i*i {
+			t.Errorf("results[%d] = %d, expected %d", i, result, i*i)
+		}
+	}
+}
+
+func TestRunBoundedCancelsOnError(t *testing.T) {
+	failure := errors.New("upload failed")
+	var started int32
+
+	err := RunBounded(context.Background(), 100, 2, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&started, 1)
+		if i == 1 {
+			return failure
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+
+	if !errors.Is(err, failure) {
+		t.Errorf("RunBounded returned %v, expected %v", err, failure)
+	}
+	if started > 3 {
+		t.Errorf("%d tasks started after the failure, expected the rest to be skipped", started)
+	}
+}